@@ -0,0 +1,81 @@
+// Command compose-migrate converts a Compose file of version 1 or 2.x to
+// version 3.
+//
+// Usage:
+//
+//	compose-migrate [-o OUTPUT] [FILE]
+//
+// The file is read from standard input if FILE is missing or "-", and the
+// migrated file is written to standard output unless OUTPUT is given. Every
+// property which could not be migrated is printed to standard error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/aanand/compose-file/migrate"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("compose-migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "write the migrated file to `OUTPUT` instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: compose-migrate [-o OUTPUT] [FILE]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Converts a Compose file of version 1 or 2.x to version 3.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	filename := flags.Arg(0)
+	source, err := readSource(filename, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "compose-migrate: %s\n", err)
+		return 1
+	}
+
+	if filename == "-" {
+		filename = ""
+	}
+	out, issues, err := migrate.File(filename, source)
+	if err != nil {
+		fmt.Fprintf(stderr, "compose-migrate: %s\n", err)
+		return 1
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(stderr, issue)
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(out)
+	} else {
+		err = ioutil.WriteFile(*output, out, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "compose-migrate: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func readSource(filename string, stdin io.Reader) ([]byte, error) {
+	if filename == "" || filename == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(filename)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const source = `version: "2"
+services:
+  web:
+    image: web
+    mem_limit: 64m
+    extends:
+      service: base
+`
+
+func TestRunFromStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(nil, strings.NewReader(source), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, `version: "3"
+services:
+  web:
+    image: web
+    deploy:
+      resources:
+        limits:
+          memory: 64m
+`, stdout.String())
+	assert.Equal(t, "6:5: services.web.extends: `extends` is not supported.\n", stderr.String())
+}
+
+func TestRunWithFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compose-migrate")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "docker-compose.yml")
+	output := filepath.Join(dir, "docker-compose.v3.yml")
+	if !assert.NoError(t, ioutil.WriteFile(input, []byte(source), 0644)) {
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-o", output, input}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, input+":6:5: services.web.extends: `extends` is not supported.\n", stderr.String())
+
+	out, err := ioutil.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `version: "3"`)
+}
+
+func TestRunErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run(nil, strings.NewReader("version: \"3\"\n"), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "compose-migrate: Unsupported Compose file version")
+
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"missing.yml"}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "missing.yml")
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"a.yml", "b.yml"}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: compose-migrate")
+}
@@ -0,0 +1,308 @@
+// Package diff compares two loaded configurations and describes the changes
+// between them.
+package diff
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/aanand/compose-file/types"
+)
+
+// ChangeKind classifies a Change
+type ChangeKind string
+
+const (
+	// Added is a resource, property or element which is only in the new
+	// configuration
+	Added ChangeKind = "added"
+	// Removed is a resource, property or element which is only in the old
+	// configuration
+	Removed ChangeKind = "removed"
+	// Changed is a property whose value is different in the new configuration
+	Changed ChangeKind = "changed"
+)
+
+// setFields are the paths of the list properties whose order has no meaning.
+// A * matches any key of a mapping.
+var setFields = []string{
+	"cap_add",
+	"cap_drop",
+	"depends_on",
+	"deploy.placement.constraints",
+	"devices",
+	"dns",
+	"dns_search",
+	"expose",
+	"external_links",
+	"links",
+	"networks.*.aliases",
+	"ports",
+	"security_opt",
+	"tmpfs",
+	"volumes",
+}
+
+// Change is a single difference between two configurations
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Section is the kind of resource which changed: service, network or
+	// volume
+	Section string `json:"section"`
+	// Name is the name of the service, network or volume
+	Name string `json:"name"`
+	// Path is the path of the property within the resource, for example
+	// deploy.resources.limits.memory, or empty if the whole resource was
+	// added or removed
+	Path string `json:"path,omitempty"`
+	// Old and New are the values before and after the change. For an element
+	// added to or removed from a set, they hold the element.
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// Configs returns the changes from old to updated, ordered by section, by
+// resource name and by property. Lists whose order has no meaning, such as
+// cap_add and ports, are compared as sets, and mappings are compared by key.
+func Configs(old, updated *types.Config) []Change {
+	var changes []Change
+
+	for _, section := range []struct {
+		name         string
+		old, updated reflect.Value
+	}{
+		{"service", reflect.ValueOf(servicesByName(old.Services)), reflect.ValueOf(servicesByName(updated.Services))},
+		{"network", reflect.ValueOf(old.Networks), reflect.ValueOf(updated.Networks)},
+		{"volume", reflect.ValueOf(old.Volumes), reflect.ValueOf(updated.Volumes)},
+	} {
+		for _, name := range unionKeys(section.old, section.updated) {
+			key := reflect.ValueOf(name)
+			changes = append(changes, diffResource(section.name, name,
+				section.old.MapIndex(key), section.updated.MapIndex(key))...)
+		}
+	}
+	return changes
+}
+
+func servicesByName(services []types.ServiceConfig) map[string]types.ServiceConfig {
+	byName := make(map[string]types.ServiceConfig, len(services))
+	for _, service := range services {
+		byName[service.Name] = service
+	}
+	return byName
+}
+
+func diffResource(section, name string, old, updated reflect.Value) []Change {
+	d := &differ{section: section, name: name}
+	switch {
+	case !old.IsValid():
+		d.add(Added, "", nil, nil)
+	case !updated.IsValid():
+		d.add(Removed, "", nil, nil)
+	default:
+		d.diff("", "", old, updated)
+	}
+	return d.changes
+}
+
+type differ struct {
+	section string
+	name    string
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path string, old, updated interface{}) {
+	d.changes = append(d.changes, Change{
+		Kind:    kind,
+		Section: d.section,
+		Name:    d.name,
+		Path:    path,
+		Old:     old,
+		New:     updated,
+	})
+}
+
+// diff compares two values of the same type at path. pattern is path with the
+// keys of mappings replaced by *, and is matched against setFields.
+func (d *differ) diff(path, pattern string, old, updated reflect.Value) {
+	switch old.Kind() {
+	case reflect.Ptr:
+		if old.IsNil() && updated.IsNil() {
+			return
+		}
+		if old.Type().Elem().Kind() == reflect.Struct {
+			d.diff(path, pattern, elemOrZero(old), elemOrZero(updated))
+			return
+		}
+		d.diffLeaf(path, interfaceOf(old), interfaceOf(updated))
+
+	case reflect.Struct:
+		if _, ok := old.Interface().(types.External); ok {
+			d.diffLeaf(path, externalValue(old), externalValue(updated))
+			return
+		}
+		for i := 0; i < old.NumField(); i++ {
+			field := old.Type().Field(i)
+			if field.Name == "Name" && path == "" && d.section == "service" {
+				continue
+			}
+			name := types.FieldName(field)
+			d.diff(types.JoinPath(path, name), types.JoinPath(pattern, name), old.Field(i), updated.Field(i))
+		}
+
+	case reflect.Map:
+		for _, key := range unionKeys(old, updated) {
+			keyValue := reflect.ValueOf(key).Convert(old.Type().Key())
+			d.diffMapEntry(types.JoinPath(path, key), types.JoinPath(pattern, "*"),
+				old.MapIndex(keyValue), updated.MapIndex(keyValue), old.Type().Elem())
+		}
+
+	case reflect.Slice:
+		if types.ContainsString(setFields, pattern) {
+			d.diffSet(path, old, updated)
+			return
+		}
+		d.diffLeaf(path, sliceOrNil(old), sliceOrNil(updated))
+
+	default:
+		d.diffLeaf(path, zeroToNil(old), zeroToNil(updated))
+	}
+}
+
+// diffMapEntry compares the values of a key in two mappings. A missing key
+// whose value is a struct is reported, and then compared as an empty struct so
+// that its properties are reported too.
+func (d *differ) diffMapEntry(path, pattern string, old, updated reflect.Value, elemType reflect.Type) {
+	if structType := types.IndirectType(elemType); structType.Kind() == reflect.Struct {
+		switch {
+		case !old.IsValid():
+			d.add(Added, path, nil, nil)
+		case !updated.IsValid():
+			d.add(Removed, path, nil, nil)
+		}
+		d.diff(path, pattern, structValue(old, structType), structValue(updated, structType))
+		return
+	}
+	switch {
+	case !old.IsValid():
+		d.add(Added, path, nil, interfaceOf(updated))
+	case !updated.IsValid():
+		d.add(Removed, path, interfaceOf(old), nil)
+	default:
+		d.diffLeaf(path, interfaceOf(old), interfaceOf(updated))
+	}
+}
+
+func (d *differ) diffSet(path string, old, updated reflect.Value) {
+	oldSet := setOf(old)
+	newSet := setOf(updated)
+	for i := 0; i < old.Len(); i++ {
+		elem := old.Index(i).Interface()
+		if !newSet[elem] {
+			d.add(Removed, path, elem, nil)
+		}
+	}
+	for i := 0; i < updated.Len(); i++ {
+		elem := updated.Index(i).Interface()
+		if !oldSet[elem] {
+			d.add(Added, path, nil, elem)
+		}
+	}
+}
+
+func (d *differ) diffLeaf(path string, old, updated interface{}) {
+	switch {
+	case reflect.DeepEqual(old, updated):
+	case old == nil:
+		d.add(Added, path, nil, updated)
+	case updated == nil:
+		d.add(Removed, path, old, nil)
+	default:
+		d.add(Changed, path, old, updated)
+	}
+}
+
+func setOf(list reflect.Value) map[interface{}]bool {
+	set := make(map[interface{}]bool, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		set[list.Index(i).Interface()] = true
+	}
+	return set
+}
+
+// unionKeys returns the sorted keys of two mappings with string keys
+func unionKeys(old, updated reflect.Value) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, mapping := range []reflect.Value{old, updated} {
+		if !mapping.IsValid() {
+			continue
+		}
+		for _, key := range mapping.MapKeys() {
+			if !seen[key.String()] {
+				seen[key.String()] = true
+				keys = append(keys, key.String())
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func elemOrZero(value reflect.Value) reflect.Value {
+	if value.IsNil() {
+		return reflect.Zero(value.Type().Elem())
+	}
+	return value.Elem()
+}
+
+func structValue(value reflect.Value, structType reflect.Type) reflect.Value {
+	for value.IsValid() && value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Zero(structType)
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return reflect.Zero(structType)
+	}
+	return value
+}
+
+// interfaceOf returns the value a pointer points to, or nil
+func interfaceOf(value reflect.Value) interface{} {
+	for value.IsValid() && value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Interface()
+}
+
+func sliceOrNil(value reflect.Value) interface{} {
+	if value.Len() == 0 {
+		return nil
+	}
+	return value.Interface()
+}
+
+func zeroToNil(value reflect.Value) interface{} {
+	if value.IsZero() {
+		return nil
+	}
+	return value.Interface()
+}
+
+// externalValue returns the name of an external resource, or nil if the
+// resource is not external
+func externalValue(value reflect.Value) interface{} {
+	external := value.Interface().(types.External)
+	if !external.External {
+		return nil
+	}
+	return external.Name
+}
@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aanand/compose-file/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(val string) *string {
+	return &val
+}
+
+func oldConfig() *types.Config {
+	return &types.Config{
+		Services: []types.ServiceConfig{
+			{
+				Name:    "web",
+				Image:   "nginx:1.10",
+				CapAdd:  []string{"NET_ADMIN", "SYS_ADMIN"},
+				Ports:   []string{"80"},
+				Command: []string{"nginx", "-g", "daemon off;"},
+				Labels:  map[string]string{"tier": "front", "team": "a"},
+			},
+			{Name: "db", Image: "postgres"},
+		},
+		Networks: map[string]types.NetworkConfig{
+			"front": {Driver: "overlay"},
+		},
+	}
+}
+
+func TestConfigsNoChanges(t *testing.T) {
+	assert.Empty(t, Configs(oldConfig(), oldConfig()))
+}
+
+func TestConfigsServiceChanges(t *testing.T) {
+	updated := oldConfig()
+	web := &updated.Services[0]
+	web.Image = "nginx:1.11"
+	web.CapAdd = []string{"SYS_ADMIN", "NET_ADMIN"}
+	web.Ports = []string{"80", "443"}
+	web.Labels = map[string]string{"tier": "web", "owner": "b"}
+
+	assert.Equal(t, []Change{
+		{Kind: Changed, Section: "service", Name: "web", Path: "image", Old: "nginx:1.10", New: "nginx:1.11"},
+		{Kind: Added, Section: "service", Name: "web", Path: "labels.owner", New: "b"},
+		{Kind: Removed, Section: "service", Name: "web", Path: "labels.team", Old: "a"},
+		{Kind: Changed, Section: "service", Name: "web", Path: "labels.tier", Old: "front", New: "web"},
+		{Kind: Added, Section: "service", Name: "web", Path: "ports", New: "443"},
+	}, Configs(oldConfig(), updated))
+}
+
+func TestConfigsOrderedLists(t *testing.T) {
+	updated := oldConfig()
+	updated.Services[0].Command = []string{"daemon off;", "-g", "nginx"}
+
+	changes := Configs(oldConfig(), updated)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, Changed, changes[0].Kind)
+	assert.Equal(t, "command", changes[0].Path)
+}
+
+func TestConfigsResources(t *testing.T) {
+	updated := oldConfig()
+	updated.Services = updated.Services[:1]
+	updated.Services = append(updated.Services, types.ServiceConfig{Name: "cache", Image: "redis"})
+	updated.Networks = nil
+	updated.Volumes = map[string]types.VolumeConfig{
+		"data": {External: types.External{External: true, Name: "data"}},
+	}
+
+	assert.Equal(t, []Change{
+		{Kind: Added, Section: "service", Name: "cache"},
+		{Kind: Removed, Section: "service", Name: "db"},
+		{Kind: Removed, Section: "network", Name: "front"},
+		{Kind: Added, Section: "volume", Name: "data"},
+	}, Configs(oldConfig(), updated))
+}
+
+func TestConfigsNestedProperties(t *testing.T) {
+	old := oldConfig()
+	old.Services[1].Environment = types.MappingWithEquals{"FOO": strPtr("1")}
+	updated := oldConfig()
+	updated.Services[1].Environment = types.MappingWithEquals{"FOO": strPtr("2"), "BAR": nil}
+	updated.Services[1].Networks = map[string]*types.ServiceNetworkConfig{
+		"front": {Aliases: []string{"database"}},
+	}
+	updated.Services[1].Ulimits = map[string]*types.UlimitsConfig{"nproc": {Single: 65535}}
+
+	assert.Equal(t, []Change{
+		{Kind: Added, Section: "service", Name: "db", Path: "environment.BAR"},
+		{Kind: Changed, Section: "service", Name: "db", Path: "environment.FOO", Old: "1", New: "2"},
+		{Kind: Added, Section: "service", Name: "db", Path: "networks.front"},
+		{Kind: Added, Section: "service", Name: "db", Path: "networks.front.aliases", New: "database"},
+		{Kind: Added, Section: "service", Name: "db", Path: "ulimits.nproc"},
+		{Kind: Added, Section: "service", Name: "db", Path: "ulimits.nproc.single", New: 65535},
+	}, Configs(old, updated))
+}
+
+func TestText(t *testing.T) {
+	updated := oldConfig()
+	updated.Services[0].Image = "nginx:1.11"
+	updated.Services[0].Ports = []string{"80", "443"}
+	updated.Services[0].CapDrop = []string{"MKNOD"}
+	updated.Services[0].Tmpfs = []types.TmpfsConfig{{Target: "/run", Size: 1024}}
+	updated.Services = updated.Services[:1]
+	updated.Networks["front"] = types.NetworkConfig{Driver: "bridge"}
+
+	assert.Equal(t, `service db: removed
+service web: cap_drop added MKNOD; image changed nginx:1.10 → nginx:1.11; ports added 443; tmpfs added /run:size=1024
+network front: driver changed overlay → bridge
+`, Text(Configs(oldConfig(), updated)))
+}
+
+func TestTextWithNilValues(t *testing.T) {
+	changes := []Change{
+		{Kind: Changed, Section: "service", Name: "web", Path: "environment.DEBUG", Old: (*string)(nil), New: strPtr("1")},
+		{Kind: Changed, Section: "service", Name: "web", Path: "environment.TOKEN", Old: strPtr("secret"), New: nil},
+		{Kind: Added, Section: "service", Name: "web", Path: "dns", New: []interface{}{"8.8.8.8", nil}},
+	}
+	assert.Equal(t,
+		"service web: environment.DEBUG changed <unset> → 1; environment.TOKEN changed secret → <unset>; dns added [8.8.8.8, <unset>]\n",
+		Text(changes))
+}
+
+func TestJSON(t *testing.T) {
+	updated := oldConfig()
+	updated.Services[0].Image = "nginx:1.11"
+
+	data, err := JSON(Configs(oldConfig(), updated))
+	assert.NoError(t, err)
+
+	var decoded []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, []map[string]interface{}{
+		{"kind": "changed", "section": "service", "name": "web", "path": "image", "old": "nginx:1.10", "new": "nginx:1.11"},
+	}, decoded)
+
+	data, err = JSON(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+}
@@ -0,0 +1,81 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Text renders changes as one line per resource, for example
+//
+//	service web: image changed nginx:1.10 → nginx:1.11; ports added 443
+func Text(changes []Change) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(changes); {
+		j := i
+		var parts []string
+		for ; j < len(changes) && sameResource(changes[i], changes[j]); j++ {
+			parts = append(parts, describe(changes[j]))
+		}
+		fmt.Fprintf(&buf, "%s %s: %s\n", changes[i].Section, changes[i].Name, strings.Join(parts, "; "))
+		i = j
+	}
+	return buf.String()
+}
+
+// JSON renders changes as an indented JSON array
+func JSON(changes []Change) ([]byte, error) {
+	if changes == nil {
+		changes = []Change{}
+	}
+	return json.MarshalIndent(changes, "", "  ")
+}
+
+func sameResource(a, b Change) bool {
+	return a.Section == b.Section && a.Name == b.Name
+}
+
+func describe(change Change) string {
+	if change.Path == "" {
+		return string(change.Kind)
+	}
+	switch change.Kind {
+	case Added:
+		if change.New == nil {
+			return fmt.Sprintf("%s added", change.Path)
+		}
+		return fmt.Sprintf("%s added %s", change.Path, formatValue(change.New))
+	case Removed:
+		if change.Old == nil {
+			return fmt.Sprintf("%s removed", change.Path)
+		}
+		return fmt.Sprintf("%s removed %s", change.Path, formatValue(change.Old))
+	default:
+		return fmt.Sprintf("%s changed %s → %s", change.Path, formatValue(change.Old), formatValue(change.New))
+	}
+}
+
+// formatValue formats a value for Text, writing lists as [a, b], following
+// pointers, and writing nil as <unset>
+func formatValue(value interface{}) string {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		return "<unset>"
+	}
+	if v.Kind() == reflect.Slice {
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatValue(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
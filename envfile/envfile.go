@@ -0,0 +1,233 @@
+// Package envfile parses files of environment variables, such as the .env
+// file of a project or the env_file of a service.
+package envfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aanand/compose-file/template"
+)
+
+var variableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ParseError is returned when a line of an env file can not be parsed
+type ParseError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Filename, e.Line, e.Message)
+}
+
+// Options controls how variables are resolved while parsing
+type Options struct {
+	// Lookup resolves variables which are not defined earlier in the file,
+	// for interpolation and for lines without a value. If nil, such variables
+	// are unset.
+	Lookup template.Mapping
+	// Interpolate enables substitution of $VAR and ${VAR} in unquoted and
+	// double-quoted values
+	Interpolate bool
+}
+
+// ParseFile reads the file at filename and returns the variables it defines
+func ParseFile(filename string, options Options) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseNamed(file, filename, options)
+}
+
+// ParseFS is like ParseFile, but reads the file called name from fsys
+func ParseFS(fsys fs.FS, name string, options Options) (map[string]string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseNamed(file, name, options)
+}
+
+func parseNamed(reader io.Reader, filename string, options Options) (map[string]string, error) {
+	env, err := Parse(reader, options)
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.Filename = filename
+	}
+	return env, err
+}
+
+// Parse reads lines of the form KEY=VALUE and returns the variables they
+// define.
+//
+// Blank lines and lines starting with # are skipped, and a leading `export `
+// is ignored. A line with only a variable name passes the variable through
+// from options.Lookup, and is skipped if it is not set there.
+//
+// Unquoted values end at the first # preceded by whitespace. Single-quoted
+// values are taken literally. Double-quoted values may span several lines and
+// support the escapes \n, \r, \t, \", \\ and \$.
+func Parse(reader io.Reader, options Options) (map[string]string, error) {
+	env := make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if value, ok := env[name]; ok {
+			return value, true
+		}
+		if options.Lookup != nil {
+			return options.Lookup(name)
+		}
+		return "", false
+	}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	nextLine := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		lineNumber++
+		return scanner.Text(), true
+	}
+
+	for {
+		line, ok := nextLine()
+		if !ok {
+			break
+		}
+		start := lineNumber
+
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) == 1 {
+			key = stripComment(key)
+		}
+		if !variableNameRegexp.MatchString(key) {
+			return nil, &ParseError{Line: start, Message: fmt.Sprintf("invalid variable name %q", key)}
+		}
+		if len(parts) == 1 {
+			if value, ok := lookup(key); ok {
+				env[key] = value
+			}
+			continue
+		}
+
+		value := strings.TrimLeft(parts[1], " \t")
+		var rest string
+		interpolate := options.Interpolate
+		switch {
+		case strings.HasPrefix(value, `"`):
+			var closed bool
+			value, rest, closed = readDoubleQuoted(value[1:], interpolate)
+			for !closed {
+				next, ok := nextLine()
+				if !ok {
+					return nil, &ParseError{Line: start, Message: fmt.Sprintf("unterminated quoted value for %s", key)}
+				}
+				var more string
+				more, rest, closed = readDoubleQuoted(next, interpolate)
+				value += "\n" + more
+			}
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, &ParseError{Line: start, Message: fmt.Sprintf("unterminated quoted value for %s", key)}
+			}
+			value, rest = value[1:end+1], value[end+2:]
+			interpolate = false
+		default:
+			value = stripComment(value)
+		}
+
+		rest = strings.TrimSpace(rest)
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, &ParseError{Line: lineNumber, Message: fmt.Sprintf("unexpected characters after quoted value for %s", key)}
+		}
+
+		if interpolate {
+			substituted, err := template.Substitute(value, lookup)
+			if err != nil {
+				return nil, &ParseError{Line: start, Message: fmt.Sprintf("invalid interpolation format for %s: %q", key, err.Template)}
+			}
+			value = substituted
+		}
+
+		env[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// stripComment removes a trailing comment, which starts at a # preceded by
+// whitespace, and any trailing whitespace
+func stripComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	return strings.TrimRight(value, " \t")
+}
+
+// readDoubleQuoted reads the content of a double-quoted value up to the closing
+// quote, and returns the unescaped content, the text after the quote, and
+// whether the closing quote was found. An escaped $ is kept as $$ if the value
+// is going to be interpolated.
+func readDoubleQuoted(text string, interpolate bool) (string, string, bool) {
+	var value strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			return value.String(), text[i+1:], true
+		case '\\':
+			if i+1 < len(text) {
+				i++
+				switch text[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 'r':
+					value.WriteByte('\r')
+				case 't':
+					value.WriteByte('\t')
+				case '$':
+					if interpolate {
+						value.WriteString("$$")
+					} else {
+						value.WriteByte('$')
+					}
+				case '"', '\\':
+					value.WriteByte(text[i])
+				default:
+					value.WriteByte('\\')
+					value.WriteByte(text[i])
+				}
+				continue
+			}
+		}
+		value.WriteByte(text[i])
+	}
+	return value.String(), "", false
+}
@@ -0,0 +1,122 @@
+package envfile
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var project = map[string]string{
+	"USER": "jenny",
+	"HOME": "/home/jenny",
+}
+
+func lookupProject(name string) (string, bool) {
+	value, ok := project[name]
+	return value, ok
+}
+
+func TestParse(t *testing.T) {
+	env, err := Parse(strings.NewReader(`
+# a comment
+FOO=bar
+  INDENTED=yes
+export EXPORTED=1
+EMPTY=
+SPACES=  padded value  
+COMMENTED=value # a comment
+HASH=value#not-a-comment
+SINGLE='$literal "value"' # a comment
+DOUBLE="quoted \"value\"\n\twith escapes"
+MULTI="first
+second"   # trailing comment
+`), Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"FOO":       "bar",
+		"INDENTED":  "yes",
+		"EXPORTED":  "1",
+		"EMPTY":     "",
+		"SPACES":    "padded value",
+		"COMMENTED": "value",
+		"HASH":      "value#not-a-comment",
+		"SINGLE":    `$literal "value"`,
+		"DOUBLE":    "quoted \"value\"\n\twith escapes",
+		"MULTI":     "first\nsecond",
+	}, env)
+}
+
+func TestParseInterpolation(t *testing.T) {
+	env, err := Parse(strings.NewReader(`
+GREETING=hello $USER
+PATH_TO_DATA="${HOME}/data"
+REUSED=${GREETING}!
+LITERAL='${HOME}'
+ESCAPED="costs \$5 or $$6"
+DEFAULT=${UNSET-fallback}
+`), Options{Lookup: lookupProject, Interpolate: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"GREETING":     "hello jenny",
+		"PATH_TO_DATA": "/home/jenny/data",
+		"REUSED":       "hello jenny!",
+		"LITERAL":      "${HOME}",
+		"ESCAPED":      "costs $5 or $6",
+		"DEFAULT":      "fallback",
+	}, env)
+
+	env, err = Parse(strings.NewReader("RAW=${HOME}\n"), Options{Lookup: lookupProject})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"RAW": "${HOME}"}, env)
+}
+
+func TestParsePassThrough(t *testing.T) {
+	env, err := Parse(strings.NewReader("USER\nUNSET\nHOME # comment\n"), Options{Lookup: lookupProject})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"USER": "jenny",
+		"HOME": "/home/jenny",
+	}, env)
+}
+
+func TestParseMalformedLines(t *testing.T) {
+	_, err := Parse(strings.NewReader("FOO=bar\n\nNOT A VARIABLE\n"), Options{})
+	assert.EqualError(t, err, `line 3: invalid variable name "NOT A VARIABLE"`)
+
+	_, err = Parse(strings.NewReader("FOO=bar\n1BAR=baz\n"), Options{})
+	assert.EqualError(t, err, `line 2: invalid variable name "1BAR"`)
+
+	_, err = Parse(strings.NewReader("FOO=bar\nBAR=\"open\nstill open\n"), Options{})
+	assert.EqualError(t, err, "line 2: unterminated quoted value for BAR")
+
+	_, err = Parse(strings.NewReader("FOO='closed' extra\n"), Options{})
+	assert.EqualError(t, err, "line 1: unexpected characters after quoted value for FOO")
+
+	_, err = Parse(strings.NewReader("FOO=bar\nBAR=${\n"), Options{Interpolate: true})
+	assert.EqualError(t, err, `line 2: invalid interpolation format for BAR: "${"`)
+}
+
+func TestParseFileReportsFilename(t *testing.T) {
+	_, err := ParseFile("testdata/malformed.env", Options{})
+	assert.EqualError(t, err, `testdata/malformed.env:2: invalid variable name "NOT A VARIABLE"`)
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"project/app.env": {Data: []byte("FOO=bar\n")},
+		"project/bad.env": {Data: []byte("FOO=bar\nNOT A VARIABLE\n")},
+	}
+
+	env, err := ParseFS(fsys, "project/app.env", Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"FOO": "bar"}, env)
+
+	_, err = ParseFS(fsys, "project/bad.env", Options{})
+	assert.EqualError(t, err, `project/bad.env:2: invalid variable name "NOT A VARIABLE"`)
+
+	_, err = ParseFS(fsys, "project/missing.env", Options{})
+	assert.True(t, os.IsNotExist(err))
+}
@@ -7,19 +7,19 @@ import (
 	"github.com/aanand/compose-file/types"
 )
 
-func Interpolate(config types.Dict, section string, mapping template.Mapping) (types.Dict, error) {
-	out := types.Dict{}
-
-	for name, item := range config {
-		if item == nil {
-			out[name] = nil
+// Interpolate substitutes variables in every string scalar of a top-level
+// section (services, networks or volumes). Keys, order, comments and
+// positions are preserved.
+func Interpolate(config *types.Node, section string, mapping template.Mapping) (*types.Node, error) {
+	out := config.Copy()
+
+	for _, item := range out.Items {
+		if item.Value.IsNull() {
 			continue
 		}
-		interpolatedItem, err := interpolateSectionItem(name, item.(types.Dict), section, mapping)
-		if err != nil {
+		if err := interpolateSectionItem(item.Key, item.Value, section, mapping); err != nil {
 			return nil, err
 		}
-		out[name] = interpolatedItem
 	}
 
 	return out, nil
@@ -27,62 +27,66 @@ func Interpolate(config types.Dict, section string, mapping template.Mapping) (t
 
 func interpolateSectionItem(
 	name string,
-	item types.Dict,
+	item *types.Node,
 	section string,
 	mapping template.Mapping,
-) (types.Dict, error) {
-
-	out := types.Dict{}
+) error {
 
-	for key, value := range item {
-		interpolatedValue, err := recursiveInterpolate(value, mapping)
+	for _, entry := range item.Items {
+		err := recursiveInterpolate(entry.Value, mapping)
 		if err != nil {
-			return nil, fmt.Errorf(
+			return fmt.Errorf(
 				"Invalid interpolation format for %#v option in %s %#v: %#v",
-				key, section, name, err.Template,
+				entry.Key, section, name, err.Template,
 			)
 		}
-		out[key] = interpolatedValue
 	}
 
-	return out, nil
+	return nil
 
 }
 
 func recursiveInterpolate(
-	value interface{},
+	node *types.Node,
 	mapping template.Mapping,
-) (interface{}, *template.InvalidTemplateError) {
+) *template.InvalidTemplateError {
+
+	if node == nil {
+		return nil
+	}
 
-	switch value := value.(type) {
+	switch node.Kind {
 
-	case string:
-		return template.Substitute(value, mapping)
+	case types.ScalarNode:
+		value, ok := node.Value.(string)
+		if !ok {
+			return nil
+		}
+		interpolated, err := template.Substitute(value, mapping)
+		if err != nil {
+			return err
+		}
+		node.Value = interpolated
+		return nil
 
-	case types.Dict:
-		out := types.Dict{}
-		for key, elem := range value {
-			interpolatedElem, err := recursiveInterpolate(elem, mapping)
-			if err != nil {
-				return nil, err
+	case types.MappingNode:
+		for _, item := range node.Items {
+			if err := recursiveInterpolate(item.Value, mapping); err != nil {
+				return err
 			}
-			out[key] = interpolatedElem
 		}
-		return out, nil
-
-	case []interface{}:
-		out := make([]interface{}, len(value))
-		for i, elem := range value {
-			interpolatedElem, err := recursiveInterpolate(elem, mapping)
-			if err != nil {
-				return nil, err
+		return nil
+
+	case types.SequenceNode:
+		for _, elem := range node.Elements {
+			if err := recursiveInterpolate(elem, mapping); err != nil {
+				return err
 			}
-			out[i] = interpolatedElem
 		}
-		return out, nil
+		return nil
 
 	default:
-		return value, nil
+		return nil
 
 	}
 
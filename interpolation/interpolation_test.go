@@ -19,25 +19,25 @@ func defaultMapping(name string) (string, bool) {
 }
 
 func TestInterpolate(t *testing.T) {
-	services := types.Dict{
-		"servicea": types.Dict{
+	services := types.NewNode(map[string]interface{}{
+		"servicea": map[string]interface{}{
 			"image":   "example:${USER}",
 			"volumes": []interface{}{"$FOO:/target"},
-			"logging": types.Dict{
+			"logging": map[string]interface{}{
 				"driver": "${FOO}",
-				"options": types.Dict{
+				"options": map[string]interface{}{
 					"user": "$USER",
 				},
 			},
 		},
-	}
-	expected := types.Dict{
-		"servicea": types.Dict{
+	})
+	expected := map[string]interface{}{
+		"servicea": map[string]interface{}{
 			"image":   "example:jenny",
 			"volumes": []interface{}{"bar:/target"},
-			"logging": types.Dict{
+			"logging": map[string]interface{}{
 				"driver": "bar",
-				"options": types.Dict{
+				"options": map[string]interface{}{
 					"user": "jenny",
 				},
 			},
@@ -45,15 +45,37 @@ func TestInterpolate(t *testing.T) {
 	}
 	result, err := Interpolate(services, "service", defaultMapping)
 	assert.NoError(t, err)
-	assert.Equal(t, expected, result)
+	assert.Equal(t, expected, result.Interface())
+}
+
+func TestInterpolateKeepsOrderAndPositions(t *testing.T) {
+	services := &types.Node{Kind: types.MappingNode}
+	service := &types.Node{Kind: types.MappingNode}
+	service.Set("image", &types.Node{
+		Kind:     types.ScalarNode,
+		Value:    "example:${USER}",
+		Position: types.Position{Line: 3, Column: 12},
+	})
+	service.Set("command", types.NewNode("run"))
+	services.Set("servicea", service)
+
+	result, err := Interpolate(services, "service", defaultMapping)
+	assert.NoError(t, err)
+
+	image := result.Lookup("servicea", "image")
+	assert.Equal(t, "example:jenny", image.Value)
+	assert.Equal(t, types.Position{Line: 3, Column: 12}, image.Position)
+	assert.Equal(t, []string{"image", "command"}, result.Lookup("servicea").Keys())
+	// the source document is left untouched
+	assert.Equal(t, "example:${USER}", services.Lookup("servicea", "image").Value)
 }
 
 func TestInvalidInterpolation(t *testing.T) {
-	services := types.Dict{
-		"servicea": types.Dict{
+	services := types.NewNode(map[string]interface{}{
+		"servicea": map[string]interface{}{
 			"image": "${",
 		},
-	}
+	})
 	_, err := Interpolate(services, "service", defaultMapping)
 	assert.EqualError(t, err, `Invalid interpolation format for "image" option in service "servicea": "${"`)
 }
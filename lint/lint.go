@@ -0,0 +1,169 @@
+// Package lint checks loaded configurations against policies, such as
+// requiring a healthcheck for every service.
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aanand/compose-file/types"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+const (
+	// SeverityError is a finding which should fail a check
+	SeverityError Severity = "error"
+	// SeverityWarning is a finding which should be fixed
+	SeverityWarning Severity = "warning"
+	// SeverityInfo is a finding which is only informational
+	SeverityInfo Severity = "info"
+	// SeverityOff disables a rule
+	SeverityOff Severity = "off"
+)
+
+// Finding is a violation of a rule
+type Finding struct {
+	// Rule is the ID of the rule which reported the finding
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Service is the name of the service the finding is about, if any
+	Service string `json:"service,omitempty"`
+	// Path is the path of the offending property, for example
+	// services.web.privileged
+	Path     string         `json:"path"`
+	Message  string         `json:"message"`
+	Position types.Position `json:"-"`
+}
+
+func (f Finding) String() string {
+	if f.Position.IsValid() {
+		return fmt.Sprintf("%s: %s: %s: %s (%s)", f.Position, f.Severity, f.Path, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Severity, f.Path, f.Message, f.Rule)
+}
+
+// Context is what a rule checks: the loaded configuration, and the document
+// it was loaded from, which gives the position of findings
+type Context struct {
+	Config *types.Config
+	// Source is the document the configuration was loaded from. It may be
+	// nil, in which case findings have no position.
+	Source *types.Node
+}
+
+// Finding returns a finding for the property at path, a list of mapping keys,
+// with the position of the last key of path which is present in the source
+func (c *Context) Finding(service string, message string, path ...string) Finding {
+	return Finding{
+		Service:  service,
+		Path:     strings.Join(path, "."),
+		Message:  message,
+		Position: c.position(path),
+	}
+}
+
+func (c *Context) position(path []string) types.Position {
+	var position types.Position
+	current := c.Source
+	for _, key := range path {
+		item := current.Item(key)
+		if item == nil {
+			break
+		}
+		position = item.KeyPosition
+		current = item.Value
+	}
+	return position
+}
+
+// Rule is a policy which configurations are checked against
+type Rule interface {
+	// ID identifies the rule, for example in severity overrides
+	ID() string
+	// Description explains what the rule checks
+	Description() string
+	// DefaultSeverity is the severity of the findings of the rule, unless it
+	// is overridden
+	DefaultSeverity() Severity
+	// Check returns the violations of the rule. The rule and severity of the
+	// findings are filled in by the Linter.
+	Check(ctx *Context) []Finding
+}
+
+// Options controls which rules a Linter runs, and how serious they are
+type Options struct {
+	Rules      []Rule
+	Severities map[string]Severity
+}
+
+// Option sets a field of Options
+type Option func(*Options)
+
+// WithRules replaces the built-in rules with rules
+func WithRules(rules ...Rule) Option {
+	return func(opts *Options) {
+		opts.Rules = rules
+	}
+}
+
+// WithSeverity overrides the severity of the rule with the given ID.
+// SeverityOff disables it.
+func WithSeverity(rule string, severity Severity) Option {
+	return func(opts *Options) {
+		opts.Severities[rule] = severity
+	}
+}
+
+// Linter checks configurations against a set of rules
+type Linter struct {
+	opts Options
+}
+
+// New returns a Linter which runs the built-in rules, unless WithRules is
+// given
+func New(options ...Option) *Linter {
+	opts := Options{
+		Rules:      BuiltinRules(),
+		Severities: map[string]Severity{},
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return &Linter{opts: opts}
+}
+
+// Rules returns the rules the linter runs
+func (l *Linter) Rules() []Rule {
+	return l.opts.Rules
+}
+
+// Severity returns the severity of rule, taking overrides into account
+func (l *Linter) Severity(rule Rule) Severity {
+	if severity, ok := l.opts.Severities[rule.ID()]; ok {
+		return severity
+	}
+	return rule.DefaultSeverity()
+}
+
+// Lint checks config against the rules, and returns the findings in the order
+// of the rules. source is the document config was loaded from, and may be nil.
+// For a configuration loaded from several files, it is the merged document
+// returned by loader.ConfigNode, which gives each finding the file it is in.
+func (l *Linter) Lint(config *types.Config, source *types.Node) []Finding {
+	ctx := &Context{Config: config, Source: source}
+	var findings []Finding
+	for _, rule := range l.opts.Rules {
+		severity := l.Severity(rule)
+		if severity == SeverityOff {
+			continue
+		}
+		for _, finding := range rule.Check(ctx) {
+			finding.Rule = rule.ID()
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,218 @@
+package lint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aanand/compose-file/loader"
+	"github.com/aanand/compose-file/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleConfig = `version: "3"
+services:
+  web:
+    image: nginx
+    privileged: true
+    healthcheck:
+      test: ["CMD", "true"]
+      disable: true
+  db:
+    image: postgres@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+    healthcheck:
+      test: ["CMD", "pg_isready"]
+    deploy:
+      resources:
+        limits:
+          memory: 512M
+  cache:
+    image: registry:5000/redis:3.2
+    healthcheck:
+      test: ["CMD", "true"]
+    deploy:
+      resources:
+        limits:
+          memory: 128M
+`
+
+func loadSample(t *testing.T) (*types.Config, *types.Node) {
+	source, err := loader.ParseYAML([]byte(sampleConfig))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	source.SetFilename("docker-compose.yml")
+	config, err := loader.Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{{Filename: "docker-compose.yml", Config: source}},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return config, source
+}
+
+func TestBuiltinRules(t *testing.T) {
+	config, source := loadSample(t)
+	findings := New().Lint(config, source)
+
+	assert.Equal(t, `docker-compose.yml:5:5: error: services.web.privileged: privileged containers have full access to the host (no-privileged)
+docker-compose.yml:4:5: warning: services.web.image: image nginx is not pinned by digest or tag (pinned-image)
+docker-compose.yml:8:7: warning: services.web.healthcheck.disable: healthcheck is disabled (healthcheck-required)
+docker-compose.yml:3:3: warning: services.web.deploy.resources.limits.memory: service has no memory limit (memory-limit-required)
+`, Text(findings))
+}
+
+func TestLintMultipleFiles(t *testing.T) {
+	base, err := loader.ParseYAML([]byte(`version: "3"
+services:
+  web:
+    image: nginx:1.11
+    healthcheck:
+      test: ["CMD", "true"]
+`))
+	assert.NoError(t, err)
+	override, err := loader.ParseYAML([]byte(`version: "3"
+services:
+  web:
+    privileged: true
+`))
+	assert.NoError(t, err)
+	configDetails := types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{
+			{Filename: "docker-compose.yml", Config: base},
+			{Filename: "docker-compose.override.yml", Config: override},
+		},
+	}
+	config, err := loader.Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	findings := New().Lint(config, loader.ConfigNode(configDetails))
+	assert.Equal(t, `docker-compose.override.yml:4:5: error: services.web.privileged: privileged containers have full access to the host (no-privileged)
+docker-compose.yml:3:3: warning: services.web.deploy.resources.limits.memory: service has no memory limit (memory-limit-required)
+`, Text(findings))
+}
+
+func TestSeverityOverrides(t *testing.T) {
+	config, source := loadSample(t)
+	linter := New(
+		WithSeverity("no-privileged", SeverityOff),
+		WithSeverity("memory-limit-required", SeverityError),
+	)
+	findings := linter.Lint(config, source)
+
+	var rules []string
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule)
+	}
+	assert.Equal(t, []string{"pinned-image", "healthcheck-required", "memory-limit-required"}, rules)
+	assert.Equal(t, SeverityError, findings[2].Severity)
+}
+
+type noLinksRule struct{}
+
+func (noLinksRule) ID() string                { return "no-links" }
+func (noLinksRule) Description() string       { return "Use networks instead of links." }
+func (noLinksRule) DefaultSeverity() Severity { return SeverityInfo }
+
+func (noLinksRule) Check(ctx *Context) []Finding {
+	var findings []Finding
+	for _, service := range ctx.Config.Services {
+		if len(service.Links) > 0 {
+			findings = append(findings, ctx.Finding(service.Name, "links are deprecated", "services", service.Name, "links"))
+		}
+	}
+	return findings
+}
+
+func TestCustomRuleWithoutSource(t *testing.T) {
+	config := &types.Config{Services: []types.ServiceConfig{{Name: "web", Links: []string{"db"}}}}
+	findings := New(WithRules(noLinksRule{})).Lint(config, nil)
+
+	assert.Equal(t, []Finding{{
+		Rule:     "no-links",
+		Severity: SeverityInfo,
+		Service:  "web",
+		Path:     "services.web.links",
+		Message:  "links are deprecated",
+	}}, findings)
+	assert.Equal(t, "info: services.web.links: links are deprecated (no-links)\n", Text(findings))
+}
+
+func TestPinnedImage(t *testing.T) {
+	config := &types.Config{Services: []types.ServiceConfig{
+		{Name: "a", Image: "nginx:1.11"},
+		{Name: "b", Image: "nginx:latest"},
+		{Name: "c", Image: "localhost:5000/nginx"},
+		{Name: "d", Image: "nginx@sha256:abc"},
+		{Name: "e"},
+	}}
+	findings := PinnedImage{}.Check(&Context{Config: config})
+
+	var services []string
+	for _, finding := range findings {
+		services = append(services, finding.Service)
+	}
+	assert.Equal(t, []string{"b", "c"}, services)
+}
+
+func TestJSON(t *testing.T) {
+	config, source := loadSample(t)
+	data, err := JSON(New(WithRules(NoPrivileged{})).Lint(config, source))
+	assert.NoError(t, err)
+
+	var decoded []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, []map[string]interface{}{{
+		"rule":     "no-privileged",
+		"severity": "error",
+		"service":  "web",
+		"path":     "services.web.privileged",
+		"message":  "privileged containers have full access to the host",
+		"filename": "docker-compose.yml",
+		"line":     float64(5),
+		"column":   float64(5),
+	}}, decoded)
+}
+
+func TestSARIF(t *testing.T) {
+	config, source := loadSample(t)
+	linter := New(WithRules(NoPrivileged{}, HealthcheckRequired{}))
+	data, err := SARIF(linter.Lint(config, source), linter.Rules())
+	assert.NoError(t, err)
+
+	var decoded struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn int }
+					}
+				}
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "2.1.0", decoded.Version)
+
+	run := decoded.Runs[0]
+	assert.Equal(t, "compose-file-lint", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Len(t, run.Results, 2)
+	assert.Equal(t, "no-privileged", run.Results[0].RuleID)
+	assert.Equal(t, "error", run.Results[0].Level)
+	location := run.Results[0].Locations[0].PhysicalLocation
+	assert.Equal(t, "docker-compose.yml", location.ArtifactLocation.URI)
+	assert.Equal(t, 5, location.Region.StartLine)
+	assert.Equal(t, "warning", run.Results[1].Level)
+}
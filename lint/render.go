@@ -0,0 +1,143 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "compose-file-lint"
+)
+
+// Text renders findings one per line
+func Text(findings []Finding) string {
+	var buf bytes.Buffer
+	for _, finding := range findings {
+		fmt.Fprintln(&buf, finding)
+	}
+	return buf.String()
+}
+
+type jsonFinding struct {
+	Finding
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// JSON renders findings as an indented JSON array
+func JSON(findings []Finding) ([]byte, error) {
+	out := make([]jsonFinding, len(findings))
+	for i, finding := range findings {
+		out[i] = jsonFinding{
+			Finding:  finding,
+			Filename: finding.Position.Filename,
+			Line:     finding.Position.Line,
+			Column:   finding.Position.Column,
+		}
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+var sarifLevels = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "note",
+}
+
+// SARIF renders findings as a SARIF 2.1.0 log, describing rules in the tool
+// section. Findings without a filename have no location.
+func SARIF(findings []Finding, rules []Rule) ([]byte, error) {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: toolName, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               rule.ID(),
+			ShortDescription: sarifMessage{Text: rule.Description()},
+		})
+	}
+	for _, finding := range findings {
+		result := sarifResult{
+			RuleID:  finding.Rule,
+			Level:   sarifLevels[finding.Severity],
+			Message: sarifMessage{Text: fmt.Sprintf("%s: %s", finding.Path, finding.Message)},
+		}
+		if finding.Position.Filename != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: finding.Position.Filename},
+			}}
+			if finding.Position.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   finding.Position.Line,
+					StartColumn: finding.Position.Column,
+				}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+	return json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+}
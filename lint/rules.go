@@ -0,0 +1,139 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/aanand/compose-file/types"
+)
+
+// BuiltinRules returns the rules a Linter runs by default
+func BuiltinRules() []Rule {
+	return []Rule{
+		NoPrivileged{},
+		PinnedImage{},
+		HealthcheckRequired{},
+		MemoryLimitRequired{},
+	}
+}
+
+// NoPrivileged reports services which run privileged containers
+type NoPrivileged struct{}
+
+// ID implements Rule
+func (NoPrivileged) ID() string { return "no-privileged" }
+
+// Description implements Rule
+func (NoPrivileged) Description() string {
+	return "Services must not run privileged containers."
+}
+
+// DefaultSeverity implements Rule
+func (NoPrivileged) DefaultSeverity() Severity { return SeverityError }
+
+// Check implements Rule
+func (NoPrivileged) Check(ctx *Context) []Finding {
+	var findings []Finding
+	for _, service := range ctx.Config.Services {
+		if service.Privileged {
+			findings = append(findings, ctx.Finding(service.Name,
+				"privileged containers have full access to the host",
+				"services", service.Name, "privileged"))
+		}
+	}
+	return findings
+}
+
+// PinnedImage reports services whose image is not pinned by a digest or by a
+// tag other than latest
+type PinnedImage struct{}
+
+// ID implements Rule
+func (PinnedImage) ID() string { return "pinned-image" }
+
+// Description implements Rule
+func (PinnedImage) Description() string {
+	return "Images must be pinned by digest or by a tag other than latest."
+}
+
+// DefaultSeverity implements Rule
+func (PinnedImage) DefaultSeverity() Severity { return SeverityWarning }
+
+// Check implements Rule
+func (PinnedImage) Check(ctx *Context) []Finding {
+	var findings []Finding
+	for _, service := range ctx.Config.Services {
+		if service.Image == "" {
+			continue
+		}
+		image, err := types.ParseImageReference(service.Image)
+		if err != nil || image.Digest != "" {
+			continue
+		}
+		if image.Tag == "" || image.Tag == types.DefaultTag {
+			findings = append(findings, ctx.Finding(service.Name,
+				fmt.Sprintf("image %s is not pinned by digest or tag", service.Image),
+				"services", service.Name, "image"))
+		}
+	}
+	return findings
+}
+
+// HealthcheckRequired reports services without a healthcheck
+type HealthcheckRequired struct{}
+
+// ID implements Rule
+func (HealthcheckRequired) ID() string { return "healthcheck-required" }
+
+// Description implements Rule
+func (HealthcheckRequired) Description() string {
+	return "Every service must have a healthcheck."
+}
+
+// DefaultSeverity implements Rule
+func (HealthcheckRequired) DefaultSeverity() Severity { return SeverityWarning }
+
+// Check implements Rule
+func (HealthcheckRequired) Check(ctx *Context) []Finding {
+	var findings []Finding
+	for _, service := range ctx.Config.Services {
+		switch {
+		case service.HealthCheck == nil:
+			findings = append(findings, ctx.Finding(service.Name,
+				"service has no healthcheck",
+				"services", service.Name))
+		case service.HealthCheck.Disable:
+			findings = append(findings, ctx.Finding(service.Name,
+				"healthcheck is disabled",
+				"services", service.Name, "healthcheck", "disable"))
+		}
+	}
+	return findings
+}
+
+// MemoryLimitRequired reports services without a memory limit
+type MemoryLimitRequired struct{}
+
+// ID implements Rule
+func (MemoryLimitRequired) ID() string { return "memory-limit-required" }
+
+// Description implements Rule
+func (MemoryLimitRequired) Description() string {
+	return "Every service must set deploy.resources.limits.memory."
+}
+
+// DefaultSeverity implements Rule
+func (MemoryLimitRequired) DefaultSeverity() Severity { return SeverityWarning }
+
+// Check implements Rule
+func (MemoryLimitRequired) Check(ctx *Context) []Finding {
+	var findings []Finding
+	for _, service := range ctx.Config.Services {
+		limits := service.Deploy.Resources.Limits
+		if limits == nil || limits.MemoryBytes == 0 {
+			findings = append(findings, ctx.Finding(service.Name,
+				"service has no memory limit",
+				"services", service.Name, "deploy", "resources", "limits", "memory"))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,66 @@
+package loader
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/aanand/compose-file/types"
+)
+
+// FuzzLoad checks that loading any YAML document returns an error instead of
+// panicking, with and without schema validation, and that a document which
+// loads still loads to the same configuration after it is written out with
+// MarshalYAML and parsed again. The seed corpus is in testdata/fuzz/FuzzLoad.
+func FuzzLoad(f *testing.F) {
+	fullExample, err := ioutil.ReadFile("full-example.yml")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(fullExample)
+	f.Add([]byte(sampleYAML))
+
+	optionSets := [][]Option{
+		nil,
+		{WithSkipValidation()},
+		{WithSkipValidation(), WithSkipInterpolation()},
+	}
+
+	f.Fuzz(func(t *testing.T, source []byte) {
+		dict, err := ParseYAML(source)
+		if err != nil {
+			return
+		}
+		for _, options := range optionSets {
+			config, _, err := LoadWithOptions(context.Background(), fuzzConfigDetails(dict), options...)
+			if err != nil {
+				continue
+			}
+
+			out, err := MarshalYAML(dict)
+			if err != nil {
+				t.Fatalf("can not write a loaded document: %s", err)
+			}
+			reparsed, err := ParseYAML(out)
+			if err != nil {
+				t.Fatalf("can not parse a written document: %s\n%s", err, out)
+			}
+			roundTripped, _, err := LoadWithOptions(context.Background(), fuzzConfigDetails(reparsed), options...)
+			if err != nil {
+				t.Fatalf("can not load a written document: %s\n%s", err, out)
+			}
+			if !reflect.DeepEqual(config, roundTripped) {
+				t.Fatalf("a written document loads differently:\n%#v\n%#v\n%s", config, roundTripped, out)
+			}
+		}
+	})
+}
+
+func fuzzConfigDetails(dict *types.Node) types.ConfigDetails {
+	return types.ConfigDetails{
+		WorkingDir:  "/home/user/project",
+		ConfigFiles: []types.ConfigFile{{Filename: "filename.yml", Config: dict}},
+		Environment: map[string]string{},
+	}
+}
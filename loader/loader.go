@@ -1,129 +1,339 @@
 package loader
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
 	"os"
+	"os/user"
 	"path"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/aanand/compose-file/envfile"
 	"github.com/aanand/compose-file/interpolation"
 	"github.com/aanand/compose-file/schema"
+	"github.com/aanand/compose-file/template"
 	"github.com/aanand/compose-file/types"
-	"github.com/docker/docker/runconfig/opts"
 	units "github.com/docker/go-units"
 	shellwords "github.com/mattn/go-shellwords"
 	"github.com/mitchellh/mapstructure"
-	yaml "gopkg.in/yaml.v2"
+	yaml "gopkg.in/yaml.v3"
 )
 
+const dotEnvFilename = ".env"
+
 var (
-	fieldNameRegexp = regexp.MustCompile("[A-Z][a-z0-9]+")
+	fieldNameRegexp   = regexp.MustCompile("[A-Z][a-z0-9]+")
+	sexagesimalRegexp = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(:[0-5]?[0-9])+(\.[0-9_]*)?$`)
+	hookErrorRegexp   = regexp.MustCompile(`(?s)^error decoding '([^']*)': (.*)$`)
+	fieldErrorRegexp  = regexp.MustCompile(`(?s)^field ([^:]+): (.*)$`)
+	decodeErrorRegexp = regexp.MustCompile(`(?s)^'([^']*)':? (.*)$`)
+	parseErrorRegexp  = regexp.MustCompile(`(?s)^cannot parse '([^']*)'(, | )(.*)$`)
 )
 
-// ParseYAML reads the bytes from a file, parses the bytes into a mapping
-// structure, and returns it.
-func ParseYAML(source []byte) (types.Dict, error) {
-	var cfg interface{}
-	if err := yaml.Unmarshal(source, &cfg); err != nil {
+// ParseYAML reads the bytes from a file, parses the bytes into a document
+// tree, and returns it.
+func ParseYAML(source []byte) (*types.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(source, &document); err != nil {
 		return nil, err
 	}
-	cfgMap, ok := cfg.(map[interface{}]interface{})
-	if !ok {
+	root := &document
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
 		return nil, fmt.Errorf("Top-level object must be a mapping")
 	}
-	converted, err := convertToStringKeysRecursive(cfgMap, "")
+	return (&yamlConverter{}).convert(root, "")
+}
+
+// ParseYAMLReader is like ParseYAML, but reads the document from reader
+func ParseYAMLReader(reader io.Reader) (*types.Node, error) {
+	source, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return ParseYAML(source)
+}
+
+// MarshalYAML writes a document tree as YAML, keeping the order of keys and
+// the comments. Anchors and aliases are not preserved; aliased values are
+// written out in full.
+func MarshalYAML(node *types.Node) ([]byte, error) {
+	document, err := toYAMLNode(node)
 	if err != nil {
 		return nil, err
 	}
-	return converted.(types.Dict), nil
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func toYAMLNode(node *types.Node) (*yaml.Node, error) {
+	if node == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+
+	var out *yaml.Node
+	switch node.Kind {
+	case types.MappingNode:
+		out = &yaml.Node{Kind: yaml.MappingNode}
+		for _, item := range node.Items {
+			value, err := toYAMLNode(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			key := &yaml.Node{
+				Kind:        yaml.ScalarNode,
+				Tag:         "!!str",
+				Value:       item.Key,
+				HeadComment: item.Comments.Head,
+				LineComment: item.Comments.Line,
+				FootComment: item.Comments.Foot,
+			}
+			out.Content = append(out.Content, key, value)
+		}
+	case types.SequenceNode:
+		out = &yaml.Node{Kind: yaml.SequenceNode}
+		for _, elem := range node.Elements {
+			value, err := toYAMLNode(elem)
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, value)
+		}
+	default:
+		out = &yaml.Node{}
+		if err := out.Encode(node.Value); err != nil {
+			return nil, err
+		}
+		if value, ok := node.Value.(string); ok && sexagesimalRegexp.MatchString(value) {
+			// YAML 1.1 parsers would read values such as 80:80 as numbers
+			out.Style = yaml.DoubleQuotedStyle
+		}
+	}
+	out.HeadComment = node.Comments.Head
+	out.LineComment = node.Comments.Line
+	out.FootComment = node.Comments.Foot
+	return out, nil
+}
+
+// ConfigDetailsFromFS parses the Compose files at filenames in fsys, and
+// returns a ConfigDetails which reads any other file from fsys as well.
+// Filenames and workingDir are slash-separated paths inside fsys.
+func ConfigDetailsFromFS(fsys fs.FS, workingDir string, filenames ...string) (types.ConfigDetails, error) {
+	configDetails := types.ConfigDetails{
+		WorkingDir: workingDir,
+		FS:         fsys,
+	}
+	for _, filename := range filenames {
+		file, err := fsys.Open(fsPath(workingDir, filename))
+		if err != nil {
+			return configDetails, err
+		}
+		config, err := ParseYAMLReader(file)
+		file.Close()
+		if err != nil {
+			return configDetails, fmt.Errorf("%s: %s", filename, err)
+		}
+		configDetails.ConfigFiles = append(configDetails.ConfigFiles, types.ConfigFile{
+			Filename: filename,
+			Config:   config,
+		})
+	}
+	return configDetails, nil
 }
 
 // Load reads a ConfigDetails and returns a fully loaded configuration
 func Load(configDetails types.ConfigDetails) (*types.Config, error) {
-	if len(configDetails.ConfigFiles) < 1 {
-		return nil, fmt.Errorf("No files specified")
+	config, _, err := LoadWithOptions(context.Background(), configDetails)
+	return config, err
+}
+
+// LoadWithWarnings is like Load, but also returns warnings for properties of
+// the configuration which are unsupported, deprecated, or ignored by the loader
+func LoadWithWarnings(configDetails types.ConfigDetails) (*types.Config, []Warning, error) {
+	return LoadWithOptions(context.Background(), configDetails)
+}
+
+// LoadStrict is like Load, but returns an UnusedKeysError if the configuration
+// contains keys which would be ignored
+func LoadStrict(configDetails types.ConfigDetails) (*types.Config, error) {
+	config, _, err := LoadWithOptions(context.Background(), configDetails, WithStrict())
+	return config, err
+}
+
+// LoadProject is like LoadWithOptions, but returns the configuration as a
+// Project
+func LoadProject(ctx context.Context, configDetails types.ConfigDetails, options ...Option) (*types.Project, []Warning, error) {
+	config, warnings, err := LoadWithOptions(ctx, configDetails, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return types.NewProject(config.Name, configDetails.WorkingDir, config), warnings, nil
+}
+
+// LoadWithOptions reads a ConfigDetails and returns a fully loaded
+// configuration, along with any warnings about it. Loading stops with the
+// error of ctx if it is cancelled.
+func LoadWithOptions(ctx context.Context, configDetails types.ConfigDetails, options ...Option) (*types.Config, []Warning, error) {
+	opts := &Options{}
+	for _, option := range options {
+		option(opts)
 	}
-	if len(configDetails.ConfigFiles) > 1 {
-		return nil, fmt.Errorf("Multiple files are not yet supported")
+
+	if len(configDetails.ConfigFiles) < 1 {
+		return nil, nil, fmt.Errorf("No files specified")
 	}
 
-	configDict := getConfigDict(configDetails)
+	configNode := ConfigNode(configDetails)
 
-	if services, ok := configDict["services"]; ok {
-		if servicesDict, ok := services.(types.Dict); ok {
-			forbidden := getProperties(servicesDict, types.ForbiddenProperties)
+	forbidden := getProperties(configNode.Lookup("services"), types.ForbiddenProperties)
+	if len(forbidden) > 0 {
+		return nil, nil, &ForbiddenPropertiesError{Properties: forbidden}
+	}
 
-			if len(forbidden) > 0 {
-				return nil, &ForbiddenPropertiesError{Properties: forbidden}
-			}
+	if !opts.SkipValidation {
+		if err := schema.Validate(configNode.Dict()); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	if err := schema.Validate(configDict); err != nil {
-		return nil, err
+	lookupShell := getLookupShell(configDetails, opts.LookupEnv)
+	lookupEnv, err := getLookupEnv(configDetails, lookupShell)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.LookupEnv = lookupEnv
+	opts.lookupShell = lookupShell
+	if opts.LookupHomeDir == nil && configDetails.FS == nil {
+		opts.LookupHomeDir = lookupSystemHomeDir
+	}
+
+	interpolate := func(section *types.Node, name string) (*types.Node, error) {
+		if opts.SkipInterpolation {
+			return section, nil
+		}
+		return interpolation.Interpolate(section, name, lookupEnv)
 	}
 
-	cfg := types.Config{}
-	version := configDict["version"].(string)
-	if version != "3" && version != "3.0" {
-		return nil, fmt.Errorf(`Unsupported Compose file version: %#v. The only version supported is "3" (or "3.0")`, version)
+	projectName := opts.ProjectName
+	if projectName == "" {
+		projectName = types.ProjectNameFromDir(configDetails.WorkingDir)
+	}
+	cfg := types.Config{Name: types.NormalizeProjectName(projectName)}
+	var unused []string
+	var version interface{} = ""
+	if versionNode := configNode.Lookup("version"); versionNode != nil {
+		version = versionNode.Interface()
+	}
+	if version != "3" && version != "3.0" && version != "3.8" {
+		return nil, nil, fmt.Errorf(`Unsupported Compose file version: %#v. The supported versions are "3" (or "3.0") and "3.8"`, version)
 	}
 
-	if services, ok := configDict["services"]; ok {
-		servicesConfig, err := interpolation.Interpolate(services.(types.Dict), "service", os.LookupEnv)
+	if services, ok := configNode.Get("services"); ok {
+		servicesConfig, err := interpolate(services, "service")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		servicesList, err := loadServices(servicesConfig, configDetails.WorkingDir)
+		servicesList, servicesUnused, err := loadServices(ctx, servicesConfig, configDetails, opts)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		cfg.Services = servicesList
+		unused = append(unused, prefixKeys("services", servicesUnused)...)
 	}
 
-	if networks, ok := configDict["networks"]; ok {
-		networksConfig, err := interpolation.Interpolate(networks.(types.Dict), "network", os.LookupEnv)
+	if networks, ok := configNode.Get("networks"); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		networksConfig, err := interpolate(networks, "network")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		networksMapping, err := loadNetworks(networksConfig)
+		networksDict, err := mappingDict(networksConfig, "networks")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		networksMapping, networksUnused, err := loadNetworks(networksDict)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		cfg.Networks = networksMapping
+		unused = append(unused, prefixKeys("networks", networksUnused)...)
 	}
 
-	if volumes, ok := configDict["volumes"]; ok {
-		volumesConfig, err := interpolation.Interpolate(volumes.(types.Dict), "volume", os.LookupEnv)
+	if volumes, ok := configNode.Get("volumes"); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		volumesConfig, err := interpolate(volumes, "volume")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		volumesMapping, err := loadVolumes(volumesConfig)
+		volumesDict, err := mappingDict(volumesConfig, "volumes")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		volumesMapping, volumesUnused, err := loadVolumes(volumesDict)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		cfg.Volumes = volumesMapping
+		unused = append(unused, prefixKeys("volumes", volumesUnused)...)
 	}
 
-	return &cfg, nil
+	if err := validateConfig(&cfg, opts); err != nil {
+		return nil, nil, err
+	}
+
+	if opts.Strict && len(unused) > 0 {
+		return nil, nil, &UnusedKeysError{Keys: unused}
+	}
+
+	warnings := getWarnings(configNode, unused)
+	warnings = append(warnings, getUnsetVariableWarnings(configNode, cfg.Services)...)
+	return &cfg, warnings, nil
 }
 
+// GetUnsupportedProperties returns the names of the unsupported properties set
+// on any service.
+//
+// Deprecated: use LoadWithWarnings, which also reports where each property is
+// set.
 func GetUnsupportedProperties(configDetails types.ConfigDetails) []string {
 	unsupported := map[string]bool{}
 
-	for _, service := range getServices(getConfigDict(configDetails)) {
-		serviceDict := service.(types.Dict)
+	services := ConfigNode(configDetails).Lookup("services")
+	for _, service := range services.Keys() {
+		serviceNode := services.Lookup(service)
 		for _, property := range types.UnsupportedProperties {
-			if _, isSet := serviceDict[property]; isSet {
+			if _, isSet := serviceNode.Get(property); isSet {
 				unsupported[property] = true
 			}
 		}
@@ -141,19 +351,23 @@ func sortedKeys(set map[string]bool) []string {
 	return keys
 }
 
+// GetDeprecatedProperties returns the deprecated properties set on any service,
+// and how to replace them.
+//
+// Deprecated: use LoadWithWarnings, which also reports where each property is
+// set.
 func GetDeprecatedProperties(configDetails types.ConfigDetails) map[string]string {
-	return getProperties(getServices(getConfigDict(configDetails)), types.DeprecatedProperties)
+	return getProperties(ConfigNode(configDetails).Lookup("services"), types.DeprecatedProperties)
 }
 
-func getProperties(services types.Dict, propertyMap map[string]string) map[string]string {
+func getProperties(services *types.Node, propertyMap map[string]string) map[string]string {
 	output := map[string]string{}
 
-	for _, service := range services {
-		if serviceDict, ok := service.(types.Dict); ok {
-			for property, description := range propertyMap {
-				if _, isSet := serviceDict[property]; isSet {
-					output[property] = description
-				}
+	for _, service := range services.Keys() {
+		serviceNode := services.Lookup(service)
+		for property, description := range propertyMap {
+			if _, isSet := serviceNode.Get(property); isSet {
+				output[property] = description
 			}
 		}
 	}
@@ -169,22 +383,69 @@ func (e *ForbiddenPropertiesError) Error() string {
 	return "Configuration contains forbidden properties"
 }
 
-// TODO: resolve multiple files into a single config
-func getConfigDict(configDetails types.ConfigDetails) types.Dict {
-	return configDetails.ConfigFiles[0].Config
+// getLookupShell returns the mapping which reads variables from the
+// environment the configuration is loaded in: lookup if it is set, or else the
+// environment of ConfigDetails. If that is nil, it is the process environment,
+// unless the configuration is read from an fs.FS, in which case no variable is
+// set.
+func getLookupShell(configDetails types.ConfigDetails, lookup template.Mapping) template.Mapping {
+	if lookup != nil {
+		return lookup
+	}
+	if configDetails.Environment != nil {
+		return func(name string) (string, bool) {
+			value, ok := configDetails.Environment[name]
+			return value, ok
+		}
+	}
+	if configDetails.FS != nil {
+		return func(string) (string, bool) {
+			return "", false
+		}
+	}
+	return os.LookupEnv
 }
 
-func getServices(configDict types.Dict) types.Dict {
-	if services, ok := configDict["services"]; ok {
-		if servicesDict, ok := services.(types.Dict); ok {
-			return servicesDict
-		}
+// getLookupEnv returns the mapping used to interpolate variables, which are
+// read from lookupShell, then from the .env file in the working directory.
+func getLookupEnv(configDetails types.ConfigDetails, lookupShell template.Mapping) (template.Mapping, error) {
+	dotEnv, err := parseEnvFile(configDetails, dotEnvFilename, envfile.Options{
+		Lookup:      lookupShell,
+		Interpolate: true,
+	})
+	if os.IsNotExist(err) {
+		return lookupShell, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return types.Dict{}
+	return func(name string) (string, bool) {
+		if value, ok := lookupShell(name); ok {
+			return value, true
+		}
+		value, ok := dotEnv[name]
+		return value, ok
+	}, nil
+}
+
+// ConfigNode merges the config files into the single document a configuration
+// is loaded from, later files overriding earlier ones. Each node keeps the
+// name of the file it comes from, so that its position can be reported, for
+// example by the lint package.
+func ConfigNode(configDetails types.ConfigDetails) *types.Node {
+	var merged *types.Node
+	for _, file := range configDetails.ConfigFiles {
+		config := file.Config.Copy()
+		config.SetFilename(file.Filename)
+		merged = merged.Merge(config)
+	}
+	return merged
 }
 
-func transform(source map[string]interface{}, target interface{}) error {
+// transform decodes source, which is found at path in the configuration, into
+// target, and returns the keys of source which had no matching field in target
+func transform(source map[string]interface{}, target interface{}, path string) ([]string, error) {
 	data := mapstructure.Metadata{}
 	config := &mapstructure.DecoderConfig{
 		DecodeHook: mapstructure.ComposeDecodeHookFunc(
@@ -195,11 +456,71 @@ func transform(source map[string]interface{}, target interface{}) error {
 	}
 	decoder, err := mapstructure.NewDecoder(config)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	targetType := reflect.TypeOf(target)
+	if err := decoder.Decode(source); err != nil {
+		return nil, locateDecodeError(targetType, path, err)
+	}
+	unused := make([]string, len(data.Unused))
+	for i, key := range data.Unused {
+		unused[i] = toYAMLPath(targetType, key)
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+// DecodeError is returned when a value has the wrong type or format for the
+// field it is loaded into, which the schema catches unless validation is
+// skipped. Path is the YAML path of the value, for example
+// services.web.ulimits.nofile.
+type DecodeError struct {
+	Path    string
+	Message string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// locateDecodeError converts an error returned by mapstructure, which reports
+// values by the names of Go fields, to a DecodeError for the YAML path of the
+// value, relative to path. If several values could not be decoded, the error
+// is for the first of them in path order.
+func locateDecodeError(target reflect.Type, path string, err error) error {
+	messages := []string{err.Error()}
+	if decodeErr, ok := err.(*mapstructure.Error); ok {
+		messages = decodeErr.Errors
+	}
+
+	var errs []*DecodeError
+	for _, message := range messages {
+		var key string
+		if match := hookErrorRegexp.FindStringSubmatch(message); match != nil {
+			key, message = match[1], match[2]
+			if match := fieldErrorRegexp.FindStringSubmatch(message); match != nil {
+				key, message = key+"."+match[1], match[2]
+			}
+		} else if match := parseErrorRegexp.FindStringSubmatch(message); match != nil {
+			key, message = match[1], match[3]
+			if match[2] == " " {
+				message = "cannot parse " + message
+			}
+		} else if match := decodeErrorRegexp.FindStringSubmatch(message); match != nil {
+			key, message = match[1], match[2]
+		}
+		errs = append(errs, &DecodeError{
+			Path:    types.JoinPath(path, toYAMLPath(target, strings.TrimPrefix(key, "."))),
+			Message: message,
+		})
 	}
-	err = decoder.Decode(source)
-	// TODO: log unused keys
-	return err
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return errs[0]
 }
 
 func transformHook(
@@ -216,6 +537,8 @@ func transformHook(
 		return transformUlimits(source, target, data)
 	case reflect.TypeOf(types.UnitBytes(0)):
 		return loadSize(data)
+	case reflect.TypeOf(types.TmpfsConfig{}):
+		return transformTmpfs(data)
 	}
 	switch target.Kind() {
 	case reflect.Struct:
@@ -224,14 +547,89 @@ func transformHook(
 	return data, nil
 }
 
-// keys needs to be converted to strings for jsonschema
-// TODO: don't use types.Dict
-func convertToStringKeysRecursive(value interface{}, keyPrefix string) (interface{}, error) {
-	if mapping, ok := value.(map[interface{}]interface{}); ok {
-		dict := make(types.Dict)
-		for key, entry := range mapping {
+// Like yaml.v3 when it decodes a document, the converter limits the share of
+// nodes which are built by expanding aliases, so that a small document can't
+// expand into an excessively large tree. The share allowed decreases from 99%
+// of small documents to 10% of very large ones.
+const (
+	aliasRatioRangeLow  = 400000
+	aliasRatioRangeHigh = 4000000
+)
+
+func allowedAliasRatio(nodes int) float64 {
+	switch {
+	case nodes <= aliasRatioRangeLow:
+		return 0.99
+	case nodes >= aliasRatioRangeHigh:
+		return 0.10
+	}
+	return 0.99 - 0.89*(float64(nodes-aliasRatioRangeLow)/float64(aliasRatioRangeHigh-aliasRatioRangeLow))
+}
+
+// yamlConverter builds a document tree from parsed YAML nodes, and counts the
+// nodes it builds to detect excessive aliasing
+type yamlConverter struct {
+	nodes      int
+	aliased    int
+	aliasDepth int
+}
+
+// convert builds a document tree from a parsed YAML node, resolving aliases
+// and merge keys. Mapping keys must be strings.
+func (c *yamlConverter) convert(node *yaml.Node, keyPrefix string) (*types.Node, error) {
+	c.nodes++
+	if c.aliasDepth > 0 {
+		c.aliased++
+	}
+	if c.aliased > 100 && c.nodes > 1000 && float64(c.aliased)/float64(c.nodes) > allowedAliasRatio(c.nodes) {
+		return nil, fmt.Errorf("document contains excessive aliasing")
+	}
+
+	position := types.Position{Line: node.Line, Column: node.Column}
+	comments := types.Comments{
+		Head: node.HeadComment,
+		Line: node.LineComment,
+		Foot: node.FootComment,
+	}
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		c.aliasDepth++
+		target, err := c.convert(node.Alias, keyPrefix)
+		c.aliasDepth--
+		if err != nil {
+			return nil, err
+		}
+		target.Alias = node.Value
+		target.Anchor = ""
+		target.Position = position
+		return target, nil
+
+	case yaml.MappingNode:
+		out := &types.Node{
+			Kind:     types.MappingNode,
+			Anchor:   node.Anchor,
+			Comments: comments,
+			Position: position,
+		}
+		var merges []*types.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Kind == yaml.ScalarNode && keyNode.ShortTag() == "!!merge" {
+				merged, err := c.convertMergeValue(valueNode, keyPrefix)
+				if err != nil {
+					return nil, err
+				}
+				merges = append(merges, merged...)
+				continue
+			}
+
+			var key interface{}
+			if err := keyNode.Decode(&key); err != nil {
+				return nil, err
+			}
 			str, ok := key.(string)
-			if !ok {
+			if !ok || keyNode.Kind != yaml.ScalarNode {
 				var location string
 				if keyPrefix == "" {
 					location = "at top level"
@@ -246,84 +644,214 @@ func convertToStringKeysRecursive(value interface{}, keyPrefix string) (interfac
 			} else {
 				newKeyPrefix = fmt.Sprintf("%s.%s", keyPrefix, str)
 			}
-			convertedEntry, err := convertToStringKeysRecursive(entry, newKeyPrefix)
+			value, err := c.convert(valueNode, newKeyPrefix)
 			if err != nil {
 				return nil, err
 			}
-			dict[str] = convertedEntry
+			out.Items = append(out.Items, &types.MapItem{
+				Key:         str,
+				KeyPosition: types.Position{Line: keyNode.Line, Column: keyNode.Column},
+				Comments: types.Comments{
+					Head: keyNode.HeadComment,
+					Line: keyNode.LineComment,
+					Foot: keyNode.FootComment,
+				},
+				Value: value,
+			})
 		}
-		return dict, nil
-	}
-	if list, ok := value.([]interface{}); ok {
-		var convertedList []interface{}
-		for index, entry := range list {
+		// keys set explicitly take precedence over merged ones
+		for _, merged := range merges {
+			for _, item := range merged.Items {
+				if _, exists := out.Get(item.Key); !exists {
+					out.Items = append(out.Items, item)
+				}
+			}
+		}
+		return out, nil
+
+	case yaml.SequenceNode:
+		out := &types.Node{
+			Kind:     types.SequenceNode,
+			Elements: []*types.Node{},
+			Anchor:   node.Anchor,
+			Comments: comments,
+			Position: position,
+		}
+		for index, entry := range node.Content {
 			newKeyPrefix := fmt.Sprintf("%s[%d]", keyPrefix, index)
-			convertedEntry, err := convertToStringKeysRecursive(entry, newKeyPrefix)
+			converted, err := c.convert(entry, newKeyPrefix)
 			if err != nil {
 				return nil, err
 			}
-			convertedList = append(convertedList, convertedEntry)
+			out.Elements = append(out.Elements, converted)
 		}
-		return convertedList, nil
+		return out, nil
+
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return &types.Node{
+			Kind:     types.ScalarNode,
+			Value:    value,
+			Anchor:   node.Anchor,
+			Comments: comments,
+			Position: position,
+		}, nil
 	}
-	return value, nil
 }
 
-func loadServices(servicesDict types.Dict, workingDir string) ([]types.ServiceConfig, error) {
+// convertMergeValue returns the mappings referenced by a `<<` merge key, in
+// order of precedence
+func (c *yamlConverter) convertMergeValue(node *yaml.Node, keyPrefix string) ([]*types.Node, error) {
+	if node.Kind == yaml.SequenceNode {
+		var merges []*types.Node
+		for _, entry := range node.Content {
+			merged, err := c.convertMergeValue(entry, keyPrefix)
+			if err != nil {
+				return nil, err
+			}
+			merges = append(merges, merged...)
+		}
+		return merges, nil
+	}
+	merged, err := c.convert(node, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if merged.Kind != types.MappingNode {
+		return nil, fmt.Errorf("Merge key in %s must refer to a mapping", keyPrefix)
+	}
+	return []*types.Node{merged}, nil
+}
+
+func loadServices(ctx context.Context, servicesNode *types.Node, configDetails types.ConfigDetails, opts *Options) ([]types.ServiceConfig, []string, error) {
 	var services []types.ServiceConfig
+	var unused []string
 
-	for name, serviceDef := range servicesDict {
-		serviceConfig, err := loadService(name, serviceDef.(types.Dict), workingDir)
+	if _, err := mappingDict(servicesNode, "services"); err != nil {
+		return nil, nil, err
+	}
+	for _, item := range servicesNode.Items {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		serviceDict, err := mappingDict(item.Value, "services."+item.Key)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		serviceConfig, serviceUnused, err := loadService(item.Key, serviceDict, configDetails, opts)
+		if err != nil {
+			return nil, nil, err
 		}
 		services = append(services, *serviceConfig)
+		unused = append(unused, prefixKeys(item.Key, serviceUnused)...)
 	}
 
-	return services, nil
+	return services, unused, nil
 }
 
-func loadService(name string, serviceDict types.Dict, workingDir string) (*types.ServiceConfig, error) {
+// mappingDict returns the content of a mapping node, or a DecodeError for path
+// if the node is anything else
+func mappingDict(node *types.Node, path string) (map[string]interface{}, error) {
+	if node.Kind != types.MappingNode {
+		return nil, &DecodeError{Path: path, Message: fmt.Sprintf("must be a mapping, got %s", typeName(node.Interface()))}
+	}
+	return node.Dict(), nil
+}
+
+func loadService(name string, serviceDict map[string]interface{}, configDetails types.ConfigDetails, opts *Options) (*types.ServiceConfig, []string, error) {
 	serviceConfig := &types.ServiceConfig{}
-	if err := transform(serviceDict, serviceConfig); err != nil {
-		return nil, err
+	unused, err := transform(withTmpfsVolumes(serviceDict), serviceConfig, "services."+name)
+	if err != nil {
+		return nil, nil, err
 	}
 	serviceConfig.Name = name
 
-	if err := resolveEnvironment(serviceConfig, serviceDict, workingDir); err != nil {
-		return nil, err
+	if err := validateService(serviceConfig, opts); err != nil {
+		return nil, nil, err
 	}
 
-	if err := resolveVolumePaths(serviceConfig.Volumes, workingDir); err != nil {
-		return nil, err
+	if err := resolveEnvironment(serviceConfig, serviceDict, configDetails, opts.LookupEnv); err != nil {
+		return nil, nil, err
+	}
+
+	if !opts.SkipResolvePaths {
+		if err := resolveVolumePaths(serviceConfig.Volumes, configDetails.WorkingDir, opts); err != nil {
+			return nil, nil, fmt.Errorf("Service %s: %s", name, err)
+		}
 	}
 
-	return serviceConfig, nil
+	return serviceConfig, withoutKeys(unused, serviceKeysLoadedSeparately), nil
 }
 
-func resolveEnvironment(serviceConfig *types.ServiceConfig, serviceDict types.Dict, workingDir string) error {
-	environment := make(map[string]string)
+// withTmpfsVolumes returns a copy of a service in which the volumes of type
+// tmpfs are moved to the tmpfs option, after the mounts already listed there
+func withTmpfsVolumes(serviceDict map[string]interface{}) map[string]interface{} {
+	volumes, ok := serviceDict["volumes"].([]interface{})
+	if !ok {
+		return serviceDict
+	}
+	var others, tmpfs []interface{}
+	for _, volume := range volumes {
+		if mapping, ok := volume.(map[string]interface{}); ok && mapping["type"] == "tmpfs" {
+			tmpfs = append(tmpfs, mapping)
+			continue
+		}
+		others = append(others, volume)
+	}
+	if len(tmpfs) == 0 {
+		return serviceDict
+	}
 
-	if envFileVal, ok := serviceDict["env_file"]; ok {
-		envFiles := loadStringOrListOfStrings(envFileVal)
+	service := make(map[string]interface{}, len(serviceDict))
+	for key, value := range serviceDict {
+		service[key] = value
+	}
+	if existing, ok := serviceDict["tmpfs"]; ok && existing != nil {
+		tmpfs = append(loadItemOrList(existing), tmpfs...)
+	}
+	service["tmpfs"] = tmpfs
+	if others == nil {
+		delete(service, "volumes")
+	} else {
+		service["volumes"] = others
+	}
+	return service
+}
 
-		var envVars []string
+// resolveEnvironment merges the env_file of a service into its environment,
+// and resolves variables given without a value from lookupEnv. Variables
+// which can not be resolved are left as nil.
+func resolveEnvironment(serviceConfig *types.ServiceConfig, serviceDict map[string]interface{}, configDetails types.ConfigDetails, lookupEnv template.Mapping) error {
+	environment := make(types.MappingWithEquals)
 
-		for _, file := range envFiles {
-			filePath := path.Join(workingDir, file)
-			fileVars, err := opts.ParseEnvFile(filePath)
+	if envFileVal, ok := serviceDict["env_file"]; ok {
+		for _, envFile := range loadEnvFiles(envFileVal) {
+			fileVars, err := parseEnvFile(configDetails, envFile.path, envfile.Options{
+				Lookup:      lookupEnv,
+				Interpolate: true,
+			})
+			if os.IsNotExist(err) && !envFile.required {
+				continue
+			}
 			if err != nil {
 				return err
 			}
-			envVars = append(envVars, fileVars...)
-		}
-
-		for k, v := range opts.ConvertKVStringsToMap(envVars) {
-			environment[k] = v
+			for k, v := range fileVars {
+				value := v
+				environment[k] = &value
+			}
 		}
 	}
 
 	for k, v := range serviceConfig.Environment {
+		if v == nil {
+			if value, ok := lookupEnv(k); ok {
+				v = &value
+			}
+		}
 		environment[k] = v
 	}
 
@@ -332,30 +860,148 @@ func resolveEnvironment(serviceConfig *types.ServiceConfig, serviceDict types.Di
 	return nil
 }
 
-func resolveVolumePaths(volumes []string, workingDir string) error {
+// parseEnvFile parses the env file at filename, relative to the working
+// directory, from the filesystem of configDetails
+func parseEnvFile(configDetails types.ConfigDetails, filename string, options envfile.Options) (map[string]string, error) {
+	if configDetails.FS == nil {
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(configDetails.WorkingDir, filename)
+		}
+		return envfile.ParseFile(filename, options)
+	}
+	return envfile.ParseFS(configDetails.FS, fsPath(configDetails.WorkingDir, filename), options)
+}
+
+// fsPath joins filename to workingDir, and converts the result to a path which
+// is valid in an fs.FS
+func fsPath(workingDir string, filename string) string {
+	name := path.Clean(filepath.ToSlash(filename))
+	if !path.IsAbs(name) {
+		name = path.Join(filepath.ToSlash(workingDir), name)
+	}
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+type envFile struct {
+	path     string
+	required bool
+}
+
+// loadEnvFiles reads the env_file option, which is a path, or a list of paths
+// and mappings with a path and whether the file is required
+func loadEnvFiles(value interface{}) []envFile {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+	var envFiles []envFile
+	for _, item := range list {
+		if mapping, ok := item.(map[string]interface{}); ok {
+			required, isSet := mapping["required"].(bool)
+			envFiles = append(envFiles, envFile{
+				path:     toString(mapping["path"]),
+				required: required || !isSet,
+			})
+			continue
+		}
+		envFiles = append(envFiles, envFile{path: toString(item), required: true})
+	}
+	return envFiles
+}
+
+// resolveVolumePaths makes the host paths of volumes absolute. A relative
+// host path, which starts with . or contains a separator, is relative to the
+// working directory, and ~ and ~name are expanded to home directories. A
+// source which is not a host path, such as data in data:/data, is a named
+// volume and is left as it is, as are container paths. Windows paths are
+// recognized on any OS.
+func resolveVolumePaths(volumes []string, workingDir string, opts *Options) error {
 	for i, mapping := range volumes {
-		parts := strings.SplitN(mapping, ":", 2)
-		if len(parts) == 1 {
+		volume, err := types.ParseVolumeSpec(mapping)
+		if err != nil {
+			if isUnresolved(opts, mapping) {
+				continue
+			}
+			return err
+		}
+		if volume.Source == "" || isUnresolved(opts, volume.Source) {
 			continue
 		}
 
-		if strings.HasPrefix(parts[0], ".") {
-			parts[0] = path.Join(workingDir, parts[0])
+		source, err := resolveHostPath(volume.Source, workingDir, opts)
+		if err != nil {
+			return fmt.Errorf("invalid volume %q: %s", mapping, err)
 		}
-		parts[0] = expandUser(parts[0])
+		volume.Source = source
 
-		volumes[i] = strings.Join(parts, ":")
+		volumes[i] = volume.String()
 	}
 
 	return nil
 }
 
-// TODO: make this more robust
-func expandUser(path string) string {
-	if strings.HasPrefix(path, "~") {
-		return strings.Replace(path, "~", os.Getenv("HOME"), 1)
+func resolveHostPath(source string, workingDir string, opts *Options) (string, error) {
+	switch {
+	case strings.HasPrefix(source, "~"):
+		return expandUser(source, opts)
+	case types.IsAbsPath(source):
+		return source, nil
+	case types.IsHostPath(source):
+		return path.Join(workingDir, strings.Replace(source, `\`, "/", -1)), nil
+	}
+	return source, nil
+}
+
+// expandUser expands a leading ~ to the home directory of the current user,
+// and ~name to the home directory of the user called name. If the home
+// directory can not be looked up, because the configuration is read from an
+// fs.FS without a LookupHomeDir, source is returned as it is.
+func expandUser(source string, opts *Options) (string, error) {
+	name, rest := source[1:], ""
+	if i := strings.IndexAny(name, `/\`); i >= 0 {
+		name, rest = name[:i], strings.Replace(name[i:], `\`, "/", -1)
+	}
+
+	var home string
+	if name == "" && opts.lookupShell != nil {
+		home, _ = opts.lookupShell("HOME")
+	}
+	if home == "" {
+		if opts.LookupHomeDir == nil {
+			return source, nil
+		}
+		var err error
+		if home, err = opts.LookupHomeDir(name); err != nil {
+			return "", fmt.Errorf("can not expand ~%s: %s", name, err)
+		}
+		if home == "" {
+			return "", fmt.Errorf("can not expand ~%s: the home directory is unknown", name)
+		}
+	}
+	if rest == "" {
+		return home, nil
 	}
-	return path
+	return path.Join(home, rest), nil
+}
+
+// lookupSystemHomeDir returns the home directory of a user from the system
+// user database
+func lookupSystemHomeDir(name string) (string, error) {
+	var u *user.User
+	var err error
+	if name == "" {
+		u, err = user.Current()
+	} else {
+		u, err = user.Lookup(name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir, nil
 }
 
 func transformUlimits(
@@ -366,36 +1012,174 @@ func transformUlimits(
 	switch value := data.(type) {
 	case int:
 		return types.UlimitsConfig{Single: value}, nil
-	case types.Dict:
-		ulimit := types.UlimitsConfig{}
-		ulimit.Soft = value["soft"].(int)
-		ulimit.Hard = value["hard"].(int)
-		return ulimit, nil
+	case map[string]interface{}:
+		soft, err := ulimitLimit(value, "soft")
+		if err != nil {
+			return data, err
+		}
+		hard, err := ulimitLimit(value, "hard")
+		if err != nil {
+			return data, err
+		}
+		return types.UlimitsConfig{Soft: soft, Hard: hard}, nil
 	default:
-		return data, fmt.Errorf("invalid type %T for ulimits", value)
+		return data, fmt.Errorf("a ulimit must be an integer or a mapping with soft and hard limits, got %s", integerTypeName(value))
+	}
+}
+
+func ulimitLimit(ulimit map[string]interface{}, kind string) (int, error) {
+	value, ok := ulimit[kind]
+	if !ok {
+		return 0, fmt.Errorf("%s limit is missing", kind)
+	}
+	limit, ok := value.(int)
+	if !ok {
+		return 0, fmt.Errorf("%s limit must be an integer, got %s", kind, integerTypeName(value))
+	}
+	return limit, nil
+}
+
+// integerTypeName is like typeName, but gives the value of a number which is
+// not an integer
+func integerTypeName(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return fmt.Sprint(number)
+	}
+	return typeName(value)
+}
+
+// typeName describes the type of a decoded YAML value in error messages
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64, float64:
+		return "a number"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a mapping"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// transformTmpfs loads a tmpfs mount in the short syntax, such as
+// /run:size=64m,mode=1777, or a volume of type tmpfs in the long syntax
+func transformTmpfs(data interface{}) (interface{}, error) {
+	switch value := data.(type) {
+	case types.TmpfsConfig:
+		return value, nil
+	case string:
+		return parseTmpfs(value)
+	case map[string]interface{}:
+		target, ok := value["target"].(string)
+		if !ok {
+			return data, fmt.Errorf("target must be a string, got %s", typeName(value["target"]))
+		}
+		tmpfs := types.TmpfsConfig{Target: target}
+		options, ok := value["tmpfs"].(map[string]interface{})
+		if !ok && value["tmpfs"] != nil {
+			return data, fmt.Errorf("tmpfs must be a mapping, got %s", typeName(value["tmpfs"]))
+		}
+		if size, ok := options["size"]; ok {
+			bytes, err := loadSize(size)
+			if err != nil {
+				return data, fmt.Errorf("invalid tmpfs size: %s", err)
+			}
+			tmpfs.Size = types.UnitBytes(bytes)
+		}
+		if mode, ok := options["mode"]; ok {
+			parsed, err := parseFileMode(mode)
+			if err != nil {
+				return data, err
+			}
+			tmpfs.Mode = parsed
+		}
+		return tmpfs, nil
+	}
+	return data, fmt.Errorf("a tmpfs mount must be a string or a mapping, got %s", typeName(data))
+}
+
+// parseTmpfs parses a tmpfs mount in the short syntax. Like in swarm mode,
+// size and mode are the only options.
+func parseTmpfs(spec string) (types.TmpfsConfig, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	tmpfs := types.TmpfsConfig{Target: parts[0]}
+	if len(parts) == 1 {
+		return tmpfs, nil
+	}
+
+	for _, option := range strings.Split(parts[1], ",") {
+		keyValue := strings.SplitN(option, "=", 2)
+		if len(keyValue) != 2 {
+			return tmpfs, fmt.Errorf("invalid tmpfs option %q in %q, expected size=<size> or mode=<mode>", option, spec)
+		}
+		switch keyValue[0] {
+		case "size":
+			size, err := loadSize(keyValue[1])
+			if err != nil {
+				return tmpfs, fmt.Errorf("invalid tmpfs size in %q: %s", spec, err)
+			}
+			tmpfs.Size = types.UnitBytes(size)
+		case "mode":
+			mode, err := parseFileMode(keyValue[1])
+			if err != nil {
+				return tmpfs, err
+			}
+			tmpfs.Mode = mode
+		default:
+			return tmpfs, fmt.Errorf("unsupported tmpfs option %q in %q, only size and mode are supported", keyValue[0], spec)
+		}
 	}
+	return tmpfs, nil
 }
 
-func loadNetworks(source types.Dict) (map[string]types.NetworkConfig, error) {
+// parseFileMode parses the permission bits of a file. A number is taken as it
+// was decoded, so it must be written in octal in YAML, such as 0755 or
+// 0o1777, while a string holds octal digits, such as 1777.
+func parseFileMode(value interface{}) (uint32, error) {
+	switch value := value.(type) {
+	case int:
+		if value >= 0 && value <= 07777 {
+			return uint32(value), nil
+		}
+		return 0, fmt.Errorf("invalid tmpfs mode %#o, expected an octal number such as 1777", value)
+	case string:
+		mode, err := strconv.ParseUint(strings.TrimPrefix(value, "0o"), 8, 32)
+		if err == nil && mode <= 07777 {
+			return uint32(mode), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid tmpfs mode %v, expected an octal number such as 1777", value)
+}
+
+func loadNetworks(source map[string]interface{}) (map[string]types.NetworkConfig, []string, error) {
 	networks := make(map[string]types.NetworkConfig)
-	err := transform(source, &networks)
+	unused, err := transform(source, &networks, "networks")
 	if err != nil {
-		return networks, err
+		return networks, nil, err
 	}
 	for name, network := range networks {
 		if network.External.External && network.External.Name == "" {
 			network.External.Name = name
+			if network.Name != "" {
+				network.External.Name = network.Name
+			}
 			networks[name] = network
 		}
 	}
-	return networks, nil
+	return networks, unused, nil
 }
 
-func loadVolumes(source types.Dict) (map[string]types.VolumeConfig, error) {
+func loadVolumes(source map[string]interface{}) (map[string]types.VolumeConfig, []string, error) {
 	volumes := make(map[string]types.VolumeConfig)
-	err := transform(source, &volumes)
+	unused, err := transform(source, &volumes, "volumes")
 	if err != nil {
-		return volumes, err
+		return volumes, nil, err
 	}
 	for name, volume := range volumes {
 		if volume.External.External && volume.External.Name == "" {
@@ -403,7 +1187,7 @@ func loadVolumes(source types.Dict) (map[string]types.VolumeConfig, error) {
 			volumes[name] = volume
 		}
 	}
-	return volumes, nil
+	return volumes, unused, nil
 }
 
 func transformStruct(
@@ -413,12 +1197,7 @@ func transformStruct(
 ) (interface{}, error) {
 	structValue, ok := data.(map[string]interface{})
 	if !ok {
-		// FIXME: this is necessary because of convertToStringKeysRecursive
-		structValue, ok = data.(types.Dict)
-		if !ok {
-			panic(fmt.Sprintf(
-				"transformStruct called with non-map type: %T, %s", data, data))
-		}
+		return data, fmt.Errorf("must be a mapping, got %s", typeName(data))
 	}
 
 	var err error
@@ -449,8 +1228,6 @@ func transformMapStringString(
 	switch value := data.(type) {
 	case map[string]interface{}:
 		return toMapStringString(value), nil
-	case types.Dict:
-		return toMapStringString(value), nil
 	case map[string]string:
 		return value, nil
 	default:
@@ -470,15 +1247,19 @@ func convertField(
 	case "healthcheck":
 		return loadHealthcheck(data)
 	case "list_or_dict_equals":
-		return loadMappingOrList(data, "="), nil
+		return loadMappingOrList(data, "=")
+	case "list_or_dict_equals_nullable":
+		return loadMappingOrListNullable(data, "=")
 	case "list_or_dict_colon":
-		return loadMappingOrList(data, ":"), nil
+		return loadMappingOrList(data, ":")
 	case "list_or_struct_map":
 		return loadListOrStructMap(data, target)
 	case "string_or_list":
-		return loadStringOrListOfStrings(data), nil
+		return loadStringOrListOfStrings(data)
+	case "item_or_list":
+		return loadItemOrList(data), nil
 	case "list_of_strings_or_numbers":
-		return loadListOfStringsOrNumbers(data), nil
+		return loadListOfStringsOrNumbers(data)
 	case "shell_command":
 		return loadShellCommand(data)
 	case "size":
@@ -497,8 +1278,6 @@ func transformExternal(
 	switch value := data.(type) {
 	case bool:
 		return map[string]interface{}{"external": value}, nil
-	case types.Dict:
-		return map[string]interface{}{"external": true, "name": value["name"]}, nil
 	case map[string]interface{}:
 		return map[string]interface{}{"external": true, "name": value["name"]}, nil
 	default:
@@ -517,7 +1296,10 @@ func toYAMLName(name string) string {
 func loadListOrStructMap(value interface{}, target reflect.Type) (interface{}, error) {
 	if list, ok := value.([]interface{}); ok {
 		mapValue := map[interface{}]interface{}{}
-		for _, name := range list {
+		for i, name := range list {
+			if _, ok := name.(string); !ok {
+				return nil, fmt.Errorf("must be a mapping or a list of strings, got %s at index %d", typeName(name), i)
+			}
 			mapValue[name] = nil
 		}
 		return mapValue, nil
@@ -526,43 +1308,99 @@ func loadListOrStructMap(value interface{}, target reflect.Type) (interface{}, e
 	return value, nil
 }
 
-func loadListOfStringsOrNumbers(value interface{}) []string {
-	list := value.([]interface{})
-	result := make([]string, len(list))
-	for i, item := range list {
-		result[i] = fmt.Sprint(item)
+func loadListOfStringsOrNumbers(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a list of strings or numbers, got %s", typeName(value))
 	}
-	return result
+	return listOfScalarsToStrings(list, "must be a list of strings or numbers")
 }
 
-func loadStringOrListOfStrings(value interface{}) []string {
+func loadItemOrList(value interface{}) []interface{} {
 	if list, ok := value.([]interface{}); ok {
-		result := make([]string, len(list))
-		for i, item := range list {
-			result[i] = fmt.Sprint(item)
+		return list
+	}
+	return []interface{}{value}
+}
+
+func loadStringOrListOfStrings(value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		return listOfScalarsToStrings(value, "must be a string or a list of strings")
+	}
+	return nil, fmt.Errorf("must be a string or a list of strings, got %s", typeName(value))
+}
+
+// listOfScalarsToStrings formats the items of list, which must not be lists or
+// mappings
+func listOfScalarsToStrings(list []interface{}, expected string) ([]string, error) {
+	result := make([]string, len(list))
+	for i, item := range list {
+		switch item.(type) {
+		case []interface{}, map[string]interface{}:
+			return nil, fmt.Errorf("%s, got %s at index %d", expected, typeName(item), i)
 		}
-		return result
+		result[i] = fmt.Sprint(item)
 	}
-	return []string{value.(string)}
+	return result, nil
 }
 
-func loadMappingOrList(mappingOrList interface{}, sep string) map[string]string {
-	if mapping, ok := mappingOrList.(types.Dict); ok {
-		return toMapStringString(mapping)
+func loadMappingOrList(mappingOrList interface{}, sep string) (map[string]string, error) {
+	if mapping, ok := mappingOrList.(map[string]interface{}); ok {
+		return toMapStringString(mapping), nil
 	}
 	if list, ok := mappingOrList.([]interface{}); ok {
 		result := make(map[string]string)
-		for _, value := range list {
-			parts := strings.SplitN(value.(string), sep, 2)
+		for i, value := range list {
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a mapping or a list of strings, got %s at index %d", typeName(value), i)
+			}
+			parts := strings.SplitN(str, sep, 2)
 			if len(parts) == 1 {
 				result[parts[0]] = ""
 			} else {
 				result[parts[0]] = parts[1]
 			}
 		}
-		return result
+		return result, nil
+	}
+	return nil, fmt.Errorf("must be a mapping or a list, got %s", typeName(mappingOrList))
+}
+
+// loadMappingOrListNullable is like loadMappingOrList, but keeps keys without a
+// value as nil instead of an empty string, so that they decode into a
+// types.MappingWithEquals
+func loadMappingOrListNullable(mappingOrList interface{}, sep string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	if mapping, ok := mappingOrList.(map[string]interface{}); ok {
+		for key, value := range mapping {
+			if value == nil {
+				result[key] = nil
+				continue
+			}
+			result[key] = toString(value)
+		}
+		return result, nil
+	}
+	if list, ok := mappingOrList.([]interface{}); ok {
+		for i, value := range list {
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a mapping or a list of strings, got %s at index %d", typeName(value), i)
+			}
+			parts := strings.SplitN(str, sep, 2)
+			if len(parts) == 1 {
+				result[parts[0]] = nil
+			} else {
+				result[parts[0]] = parts[1]
+			}
+		}
+		return result, nil
 	}
-	panic(fmt.Errorf("expected a map or a slice, got: %#v", mappingOrList))
+	return nil, fmt.Errorf("must be a mapping or a list, got %s", typeName(mappingOrList))
 }
 
 func loadShellCommand(value interface{}) (interface{}, error) {
@@ -586,7 +1424,7 @@ func loadSize(value interface{}) (int64, error) {
 	case string:
 		return units.RAMInBytes(value)
 	}
-	panic(fmt.Errorf("invalid type for size %T", value))
+	return 0, fmt.Errorf("a size must be a number or a string such as 64m, got %s", typeName(value))
 }
 
 func toMapStringString(value map[string]interface{}) map[string]string {
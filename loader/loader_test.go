@@ -1,18 +1,23 @@
 package loader
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/aanand/compose-file/types"
 	"github.com/stretchr/testify/assert"
 )
 
-func buildConfigDetails(source types.Dict) types.ConfigDetails {
+func buildConfigDetails(source *types.Node) types.ConfigDetails {
 	workingDir, err := os.Getwd()
 	if err != nil {
 		panic(err)
@@ -57,39 +62,39 @@ networks:
         - subnet: 172.28.0.0/16
 `
 
-var sampleDict = types.Dict{
+var sampleDict = map[string]interface{}{
 	"version": "3",
-	"services": types.Dict{
-		"foo": types.Dict{
-			"image": "busybox",
-			"networks": types.Dict{"with_me": nil},
+	"services": map[string]interface{}{
+		"foo": map[string]interface{}{
+			"image":    "busybox",
+			"networks": map[string]interface{}{"with_me": nil},
 		},
-		"bar": types.Dict{
+		"bar": map[string]interface{}{
 			"image":       "busybox",
 			"environment": []interface{}{"FOO=1"},
-			"networks": []interface{}{"with_ipam"},
+			"networks":    []interface{}{"with_ipam"},
 		},
 	},
-	"volumes": types.Dict{
-		"hello": types.Dict{
+	"volumes": map[string]interface{}{
+		"hello": map[string]interface{}{
 			"driver": "default",
-			"driver_opts": types.Dict{
+			"driver_opts": map[string]interface{}{
 				"beep": "boop",
 			},
 		},
 	},
-	"networks": types.Dict{
-		"default": types.Dict{
+	"networks": map[string]interface{}{
+		"default": map[string]interface{}{
 			"driver": "bridge",
-			"driver_opts": types.Dict{
+			"driver_opts": map[string]interface{}{
 				"beep": "boop",
 			},
 		},
-		"with_ipam": types.Dict{
-			"ipam": types.Dict{
+		"with_ipam": map[string]interface{}{
+			"ipam": map[string]interface{}{
 				"driver": "default",
 				"config": []interface{}{
-					types.Dict{
+					map[string]interface{}{
 						"subnet": "172.28.0.0/16",
 					},
 				},
@@ -103,7 +108,7 @@ var sampleConfig = types.Config{
 		{
 			Name:        "foo",
 			Image:       "busybox",
-			Environment: map[string]string{},
+			Environment: types.MappingWithEquals{},
 			Networks: map[string]*types.ServiceNetworkConfig{
 				"with_me": nil,
 			},
@@ -111,7 +116,7 @@ var sampleConfig = types.Config{
 		{
 			Name:        "bar",
 			Image:       "busybox",
-			Environment: map[string]string{"FOO": "1"},
+			Environment: types.MappingWithEquals{"FOO": strPtr("1")},
 			Networks: map[string]*types.ServiceNetworkConfig{
 				"with_ipam": nil,
 			},
@@ -150,11 +155,55 @@ func TestParseYAML(t *testing.T) {
 	if !assert.NoError(t, err) {
 		return
 	}
-	assert.Equal(t, sampleDict, dict)
+	assert.Equal(t, sampleDict, dict.Interface())
+}
+
+func TestParseYAMLKeepsOrderAndPositions(t *testing.T) {
+	dict, err := ParseYAML([]byte(sampleYAML))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"version", "services", "volumes", "networks"}, dict.Keys())
+	assert.Equal(t, []string{"foo", "bar"}, dict.Lookup("services").Keys())
+
+	image := dict.Lookup("services", "bar", "image")
+	assert.Equal(t, "busybox", image.Value)
+	assert.Equal(t, types.Position{Line: 9, Column: 12}, image.Position)
+	assert.Equal(t, 8, dict.Lookup("services").Item("bar").KeyPosition.Line)
+}
+
+func TestParseYAMLAnchorsAndComments(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+x-defaults: &defaults
+  image: busybox
+  environment: [FOO=1]
+services:
+  # the web frontend
+  web:
+    <<: *defaults
+    image: nginx # pinned below
+  worker: *defaults
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "defaults", dict.Lookup("x-defaults").Anchor)
+	assert.Equal(t, "defaults", dict.Lookup("services", "worker").Alias)
+	assert.Equal(t, "# the web frontend", dict.Lookup("services").Item("web").Comments.Head)
+	assert.Equal(t, "# pinned below", dict.Lookup("services", "web", "image").Comments.Line)
+	assert.Equal(t, map[string]interface{}{
+		"image":       "nginx",
+		"environment": []interface{}{"FOO=1"},
+	}, dict.Lookup("services", "web").Interface())
+	assert.Equal(t, map[string]interface{}{
+		"image":       "busybox",
+		"environment": []interface{}{"FOO=1"},
+	}, dict.Lookup("services", "worker").Interface())
 }
 
 func TestLoad(t *testing.T) {
-	actual, err := Load(buildConfigDetails(sampleDict))
+	actual, err := Load(buildConfigDetails(types.NewNode(sampleDict)))
 	if !assert.NoError(t, err) {
 		return
 	}
@@ -173,6 +222,50 @@ func TestParseAndLoad(t *testing.T) {
 	assert.Equal(t, sampleConfig.Volumes, actual.Volumes)
 }
 
+func TestLoadMultipleFiles(t *testing.T) {
+	base, err := ParseYAML([]byte(`
+version: "3"
+services:
+  foo:
+    image: busybox
+    environment:
+      FOO: "1"
+`))
+	assert.NoError(t, err)
+	override, err := ParseYAML([]byte(`
+version: "3"
+services:
+  foo:
+    image: alpine
+    environment:
+      BAR: "2"
+  bar:
+    image: busybox
+`))
+	assert.NoError(t, err)
+
+	configDetails := buildConfigDetails(base)
+	configDetails.ConfigFiles = append(configDetails.ConfigFiles,
+		types.ConfigFile{Filename: "override.yml", Config: override})
+
+	config, err := Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []types.ServiceConfig{
+		{
+			Name:        "foo",
+			Image:       "alpine",
+			Environment: types.MappingWithEquals{"FOO": strPtr("1"), "BAR": strPtr("2")},
+		},
+		{
+			Name:        "bar",
+			Image:       "busybox",
+			Environment: types.MappingWithEquals{},
+		},
+	}, config.Services)
+}
+
 func TestInvalidTopLevelObjectType(t *testing.T) {
 	_, err := loadYAML("1")
 	assert.Error(t, err)
@@ -281,6 +374,42 @@ services:
 `)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "version must be a string")
+
+	dict, err := ParseYAML([]byte(`
+version: 3
+services:
+  foo:
+    image: busybox
+`))
+	assert.NoError(t, err)
+	_, _, err = LoadWithOptions(context.Background(), buildConfigDetails(dict), WithSkipValidation())
+	assert.EqualError(t, err, `Unsupported Compose file version: 3. The supported versions are "3" (or "3.0") and "3.8"`)
+}
+
+func TestLaterPropertiesNeedVersion38(t *testing.T) {
+	source := `
+services:
+  web:
+    image: busybox
+    deploy:
+      rollback_config:
+        parallelism: 2
+networks:
+  front:
+    driver: overlay
+    attachable: true
+`
+	for _, version := range []string{"3", "3.0"} {
+		_, err := loadYAML(fmt.Sprintf("version: %q\n", version) + source)
+		assert.EqualError(t, err, "services.web.deploy Additional property rollback_config is not allowed", version)
+	}
+
+	config, err := loadYAML(`version: "3.8"` + "\n" + source)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint64(2), *config.Services[0].Deploy.RollbackConfig.Parallelism)
+	assert.True(t, config.Networks["front"].Attachable)
 }
 
 func TestV1Unsupported(t *testing.T) {
@@ -376,18 +505,77 @@ services:
 `)
 	assert.NoError(t, err)
 
-	expected := map[string]string{
-		"FOO":  "1",
-		"BAR":  "2",
-		"BAZ":  "2.5",
-		"QUUX": "",
+	expected := types.MappingWithEquals{
+		"FOO":  strPtr("1"),
+		"BAR":  strPtr("2"),
+		"BAZ":  strPtr("2.5"),
+		"QUUX": strPtr(""),
 	}
 
 	assert.Equal(t, 2, len(config.Services))
 
-	for _, service := range config.Services {
-		assert.Equal(t, expected, service.Environment)
+	assert.Equal(t, "list-env", config.Services[1].Name)
+	assert.Equal(t, expected, config.Services[1].Environment)
+
+	// a null value passes the variable through from the environment
+	expected["QUUX"] = nil
+	assert.Equal(t, "dict-env", config.Services[0].Name)
+	assert.Equal(t, expected, config.Services[0].Environment)
+}
+
+func TestPassThroughEnvironment(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  list-env:
+    image: busybox
+    environment:
+      - FOO
+      - EMPTY
+      - MISSING
+  dict-env:
+    image: busybox
+    environment:
+      FOO:
+      MISSING:
+`))
+	assert.NoError(t, err)
+
+	configDetails := buildConfigDetails(dict)
+	configDetails.Environment = map[string]string{"FOO": "from shell", "EMPTY": ""}
+
+	config, warnings, err := LoadWithWarnings(configDetails)
+	if !assert.NoError(t, err) {
+		return
 	}
+	assert.Equal(t, types.MappingWithEquals{
+		"FOO":     strPtr("from shell"),
+		"EMPTY":   strPtr(""),
+		"MISSING": nil,
+	}, config.Services[0].Environment)
+	assert.Equal(t, types.MappingWithEquals{
+		"FOO":     strPtr("from shell"),
+		"MISSING": nil,
+	}, config.Services[1].Environment)
+
+	assert.Equal(t, []Warning{
+		{
+			Kind:     WarningUnset,
+			Section:  "service",
+			Name:     "list-env",
+			Property: "environment.MISSING",
+			Message:  "MISSING is not set in the environment, it will not be passed to the container",
+			Position: types.Position{Filename: "filename.yml", Line: 9, Column: 9},
+		},
+		{
+			Kind:     WarningUnset,
+			Section:  "service",
+			Name:     "dict-env",
+			Property: "environment.MISSING",
+			Message:  "MISSING is not set in the environment, it will not be passed to the container",
+			Position: types.Position{Filename: "filename.yml", Line: 14, Column: 7},
+		},
+	}, warnings)
 }
 
 func TestInvalidEnvironmentValue(t *testing.T) {
@@ -450,102 +638,613 @@ volumes:
 	assert.Equal(t, home, config.Volumes["test"].Driver)
 }
 
-func TestUnsupportedProperties(t *testing.T) {
+func TestInterpolationFromDotEnv(t *testing.T) {
+	workingDir, err := ioutil.TempDir("", "compose-file")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(workingDir)
+
+	err = ioutil.WriteFile(filepath.Join(workingDir, ".env"), []byte(`
+# project defaults
+export IMAGE=busybox
+TAG="1.25"
+FROM_SHELL=dotenv
+`), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
 	dict, err := ParseYAML([]byte(`
 version: "3"
 services:
-  web:
-    image: web
-    build: ./web
-    links:
-      - bar
-  db:
-    image: db
-    build: ./db
+  test:
+    image: ${IMAGE}:${TAG}
+    labels:
+      - shell=${FROM_SHELL}
+      - default=${UNSET-inline}
 `))
 	assert.NoError(t, err)
 
 	configDetails := buildConfigDetails(dict)
+	configDetails.WorkingDir = workingDir
+	configDetails.Environment = map[string]string{"FROM_SHELL": "shell"}
 
-	_, err = Load(configDetails)
-	assert.NoError(t, err)
+	config, err := Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "busybox:1.25", config.Services[0].Image)
+	assert.Equal(t, map[string]string{
+		"shell":   "shell",
+		"default": "inline",
+	}, config.Services[0].Labels)
+}
 
-	unsupported := GetUnsupportedProperties(configDetails)
-	assert.Equal(t, []string{"build", "links"}, unsupported)
+func TestMalformedDotEnv(t *testing.T) {
+	workingDir, err := ioutil.TempDir("", "compose-file")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(workingDir)
+
+	err = ioutil.WriteFile(filepath.Join(workingDir, ".env"), []byte("FOO=1\nNOT A VARIABLE\n"), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	configDetails := buildConfigDetails(types.NewNode(sampleDict))
+	configDetails.WorkingDir = workingDir
+
+	_, err = Load(configDetails)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), ".env:2: invalid variable name")
 }
 
-func TestDeprecatedProperties(t *testing.T) {
+func TestEnvFiles(t *testing.T) {
+	workingDir, err := ioutil.TempDir("", "compose-file")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(workingDir)
+
+	err = ioutil.WriteFile(filepath.Join(workingDir, "web.env"), []byte(`
+DATABASE_URL="postgres://${DB_HOST}/app" # interpolated
+GREETING='hello $USER'
+PASSED_THROUGH
+`), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
 	dict, err := ParseYAML([]byte(`
-version: "3"
+version: "3.8"
 services:
   web:
     image: web
-    container_name: web
-  db:
-    image: db
-    container_name: db
-    expose: ["5434"]
+    env_file:
+      - web.env
+      - path: missing.env
+        required: false
 `))
 	assert.NoError(t, err)
 
 	configDetails := buildConfigDetails(dict)
+	configDetails.WorkingDir = workingDir
+	configDetails.Environment = map[string]string{
+		"DB_HOST":        "db",
+		"PASSED_THROUGH": "from shell",
+	}
 
+	config, err := Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.MappingWithEquals{
+		"DATABASE_URL":   strPtr("postgres://db/app"),
+		"GREETING":       strPtr("hello $USER"),
+		"PASSED_THROUGH": strPtr("from shell"),
+	}, config.Services[0].Environment)
+
+	dict, err = ParseYAML([]byte(`
+version: "3.8"
+services:
+  web:
+    image: web
+    env_file:
+      - path: missing.env
+`))
+	assert.NoError(t, err)
+
+	configDetails.ConfigFiles[0].Config = dict
 	_, err = Load(configDetails)
+	assert.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestEnvFileWithAbsolutePath(t *testing.T) {
+	envDir, err := ioutil.TempDir("", "compose-file")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(envDir)
+
+	envFile := filepath.Join(envDir, "app.env")
+	err = ioutil.WriteFile(envFile, []byte("GREETING=hello\n"), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	dict, err := ParseYAML([]byte(fmt.Sprintf(`
+version: "3"
+services:
+  web:
+    image: web
+    env_file: %q
+`, envFile)))
 	assert.NoError(t, err)
 
-	deprecated := GetDeprecatedProperties(configDetails)
-	assert.Equal(t, 2, len(deprecated))
-	assert.Contains(t, deprecated, "container_name")
-	assert.Contains(t, deprecated, "expose")
+	configDetails := buildConfigDetails(dict)
+	configDetails.WorkingDir = "/does/not/exist"
+
+	config, err := Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.MappingWithEquals{"GREETING": strPtr("hello")}, config.Services[0].Environment)
 }
 
-func TestForbiddenProperties(t *testing.T) {
-	_, err := loadYAML(`
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"project/docker-compose.yml": {Data: []byte(`
 version: "3"
 services:
-  foo:
-    image: busybox
-    volumes:
-      - /data
-    volume_driver: some-driver
-  bar:
-    extends:
-      service: foo
-`)
+  web:
+    image: ${IMAGE}
+    env_file: web.env
+`)},
+		"project/docker-compose.override.yml": {Data: []byte(`
+version: "3"
+services:
+  web:
+    environment:
+      DEBUG: "1"
+`)},
+		"project/.env":    {Data: []byte("IMAGE=nginx\n")},
+		"project/web.env": {Data: []byte("GREETING=hello\n")},
+	}
 
-	assert.Error(t, err)
-	assert.IsType(t, &ForbiddenPropertiesError{}, err)
-	fmt.Println(err)
-	forbidden := err.(*ForbiddenPropertiesError).Properties
+	configDetails, err := ConfigDetailsFromFS(fsys, "project", "docker-compose.yml", "docker-compose.override.yml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	configDetails.Environment = map[string]string{}
 
-	assert.Equal(t, 2, len(forbidden))
-	assert.Contains(t, forbidden, "volume_driver")
-	assert.Contains(t, forbidden, "extends")
+	config, err := Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "nginx", config.Services[0].Image)
+	assert.Equal(t, types.MappingWithEquals{
+		"GREETING": strPtr("hello"),
+		"DEBUG":    strPtr("1"),
+	}, config.Services[0].Environment)
+
+	_, err = ConfigDetailsFromFS(fsys, "project", "missing.yml")
+	assert.True(t, os.IsNotExist(err))
 }
 
-func durationPtr(value time.Duration) *time.Duration {
-	return &value
+func TestLoadFromFSIsolatedFromProcess(t *testing.T) {
+	os.Setenv("COMPOSE_FS_TEST_IMAGE", "nginx")
+	defer os.Unsetenv("COMPOSE_FS_TEST_IMAGE")
+
+	fsys := fstest.MapFS{
+		"project/docker-compose.yml": {Data: []byte(`
+version: "3"
+services:
+  web:
+    image: busybox${COMPOSE_FS_TEST_IMAGE}
+    volumes:
+      - ~/data:/data
+`)},
+	}
+	configDetails, err := ConfigDetailsFromFS(fsys, "project", "docker-compose.yml")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	config, err := Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "busybox", config.Services[0].Image)
+	assert.Equal(t, []string{"~/data:/data"}, config.Services[0].Volumes)
+
+	configDetails.Environment = map[string]string{"HOME": "/home/user"}
+	config, err = Load(configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"/home/user/data:/data"}, config.Services[0].Volumes)
+
+	configDetails.Environment = nil
+	config, _, err = LoadWithOptions(context.Background(), configDetails, WithLookupHomeDir(func(name string) (string, error) {
+		return "/home/lookup", nil
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"/home/lookup/data:/data"}, config.Services[0].Volumes)
 }
 
-func int64Ptr(value int64) *int64 {
-	return &value
+func TestParseYAMLExcessiveAliasing(t *testing.T) {
+	source := "version: \"3\"\na: &a [x, x, x, x, x, x, x, x, x, x]\n"
+	previous := "a"
+	for _, name := range []string{"b", "c", "d", "e", "f", "g", "h", "i"} {
+		source += fmt.Sprintf("%s: &%s [*%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s]\n",
+			name, name, previous, previous, previous, previous, previous, previous, previous, previous, previous, previous)
+		previous = name
+	}
+
+	start := time.Now()
+	_, err := ParseYAML([]byte(source))
+	assert.EqualError(t, err, "document contains excessive aliasing")
+	assert.True(t, time.Since(start) < 5*time.Second)
 }
 
-func uint64Ptr(value uint64) *uint64 {
-	return &value
+func TestParseYAMLReader(t *testing.T) {
+	dict, err := ParseYAMLReader(strings.NewReader(sampleYAML))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, sampleDict, dict.Interface())
 }
 
-func TestFullExample(t *testing.T) {
-	bytes, err := ioutil.ReadFile("full-example.yml")
+func TestLoadWithOptions(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: ${IMAGE}
+    volumes:
+      - ./data:/data
+    deploy:
+      resources:
+        foo: bar
+`))
 	assert.NoError(t, err)
+	configDetails := buildConfigDetails(dict)
 
-	config, err := loadYAML(string(bytes))
+	lookup := func(name string) (string, bool) {
+		if name == "IMAGE" {
+			return "nginx", true
+		}
+		return "", false
+	}
+
+	config, _, err := LoadWithOptions(context.Background(), configDetails,
+		WithLookupEnv(lookup), WithProjectName("myproject"))
 	if !assert.NoError(t, err) {
 		return
 	}
+	assert.Equal(t, "myproject", config.Name)
+	assert.Equal(t, "nginx", config.Services[0].Image)
+	assert.Equal(t, []string{configDetails.WorkingDir + "/data:/data"}, config.Services[0].Volumes)
 
-	workingDir, err := os.Getwd()
-	assert.NoError(t, err)
+	config, _, err = LoadWithOptions(context.Background(), configDetails,
+		WithSkipInterpolation(), WithSkipResolvePaths())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "${IMAGE}", config.Services[0].Image)
+	assert.Equal(t, []string{"./data:/data"}, config.Services[0].Volumes)
+
+	_, _, err = LoadWithOptions(context.Background(), configDetails, WithStrict())
+	assert.IsType(t, &UnusedKeysError{}, err)
+}
+
+func TestLoadProject(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+networks:
+  outside:
+    external: true
+`))
+	assert.NoError(t, err)
+	configDetails := buildConfigDetails(dict)
+	configDetails.WorkingDir = "/src/My_Project"
+
+	project, _, err := LoadProject(context.Background(), configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "my_project", project.Name)
+	assert.Equal(t, "my_project", project.Config.Name)
+	assert.Equal(t, "my_project_default", project.NetworkName("default"))
+	assert.Equal(t, "outside", project.NetworkName("outside"))
+	assert.Contains(t, project.Networks(), "default")
+
+	project, _, err = LoadProject(context.Background(), configDetails, WithProjectName("Other.Name"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "othername", project.Name)
+}
+
+func TestLoadWithOptionsSkipValidation(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    helicopter: true
+`))
+	assert.NoError(t, err)
+
+	_, err = Load(buildConfigDetails(dict))
+	assert.Error(t, err)
+
+	config, warnings, err := LoadWithOptions(context.Background(), buildConfigDetails(dict), WithSkipValidation())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "web", config.Services[0].Image)
+	assert.Equal(t, "helicopter", warnings[0].Property)
+}
+
+func TestLoadWithOptionsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := LoadWithOptions(ctx, buildConfigDetails(types.NewNode(sampleDict)))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestUnsupportedProperties(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    build: ./web
+    links:
+      - bar
+  db:
+    image: db
+    build: ./db
+`))
+	assert.NoError(t, err)
+
+	configDetails := buildConfigDetails(dict)
+
+	_, err = Load(configDetails)
+	assert.NoError(t, err)
+
+	unsupported := GetUnsupportedProperties(configDetails)
+	assert.Equal(t, []string{"build", "links"}, unsupported)
+}
+
+func TestDeprecatedProperties(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    container_name: web
+  db:
+    image: db
+    container_name: db
+    expose: ["5434"]
+`))
+	assert.NoError(t, err)
+
+	configDetails := buildConfigDetails(dict)
+
+	_, err = Load(configDetails)
+	assert.NoError(t, err)
+
+	deprecated := GetDeprecatedProperties(configDetails)
+	assert.Equal(t, 2, len(deprecated))
+	assert.Contains(t, deprecated, "container_name")
+	assert.Contains(t, deprecated, "expose")
+}
+
+func TestForbiddenProperties(t *testing.T) {
+	_, err := loadYAML(`
+version: "3"
+services:
+  foo:
+    image: busybox
+    volumes:
+      - /data
+    volume_driver: some-driver
+  bar:
+    extends:
+      service: foo
+`)
+
+	assert.Error(t, err)
+	assert.IsType(t, &ForbiddenPropertiesError{}, err)
+	fmt.Println(err)
+	forbidden := err.(*ForbiddenPropertiesError).Properties
+
+	assert.Equal(t, 2, len(forbidden))
+	assert.Contains(t, forbidden, "volume_driver")
+	assert.Contains(t, forbidden, "extends")
+}
+
+func TestLoadWithWarnings(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    build: ./web
+    env_file: ./example1.env
+    deploy:
+      resources:
+        foo: bar
+        limits:
+          memory: 5M
+  db:
+    image: db
+    container_name: db
+`))
+	assert.NoError(t, err)
+
+	config, warnings, err := LoadWithWarnings(buildConfigDetails(dict))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, len(config.Services))
+	assert.Equal(t, []Warning{
+		{
+			Kind:     WarningUnsupported,
+			Section:  "service",
+			Name:     "web",
+			Property: "build",
+			Message:  "build is not supported and will be ignored. " + types.UnsupportedPropertyHints["build"],
+			Position: types.Position{Filename: "filename.yml", Line: 6, Column: 5},
+		},
+		{
+			Kind:     WarningDeprecated,
+			Section:  "service",
+			Name:     "db",
+			Property: "container_name",
+			Message:  types.DeprecatedProperties["container_name"],
+			Position: types.Position{Filename: "filename.yml", Line: 15, Column: 5},
+		},
+		{
+			Kind:     WarningIgnored,
+			Section:  "service",
+			Name:     "web",
+			Property: "deploy.resources.foo",
+			Message:  "unknown key, it will be ignored",
+			Position: types.Position{Filename: "filename.yml", Line: 10, Column: 9},
+		},
+	}, warnings)
+
+	assert.Equal(t, "services.web.deploy.resources.foo", warnings[2].Path())
+	assert.Equal(t,
+		"filename.yml:6:5: services.web.build: build is not supported and will be ignored. "+
+			"Build and push the image beforehand, and reference it using image.",
+		warnings[0].String())
+}
+
+func TestLoadWithWarningsForNetworksAndVolumes(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    links: [db]
+networks:
+  front:
+    external:
+      name: front_network
+volumes:
+  data:
+    external:
+      name: data_volume
+`))
+	assert.NoError(t, err)
+
+	_, warnings, err := LoadWithWarnings(buildConfigDetails(dict))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Warning{
+		{
+			Kind:     WarningUnsupported,
+			Section:  "service",
+			Name:     "web",
+			Property: "links",
+			Message: "links is not supported and will be ignored. " +
+				"Use networks instead - services on the same network can reach each other by name.",
+			Position: types.Position{Filename: "filename.yml", Line: 6, Column: 5},
+		},
+		{
+			Kind:     WarningDeprecated,
+			Section:  "network",
+			Name:     "front",
+			Property: "external.name",
+			Message:  types.DeprecatedNetworkProperties["external.name"],
+			Position: types.Position{Filename: "filename.yml", Line: 10, Column: 7},
+		},
+		{
+			Kind:     WarningDeprecated,
+			Section:  "volume",
+			Name:     "data",
+			Property: "external.name",
+			Message:  types.DeprecatedVolumeProperties["external.name"],
+			Position: types.Position{Filename: "filename.yml", Line: 14, Column: 7},
+		},
+	}, warnings)
+
+	assert.Equal(t, "networks.front.external.name", warnings[1].Path())
+	assert.Equal(t, "volumes.data.external.name", warnings[2].Path())
+}
+
+func TestLoadStrictRejectsUnusedKeys(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    deploy:
+      resources:
+        foo: bar
+`))
+	assert.NoError(t, err)
+
+	_, err = LoadStrict(buildConfigDetails(dict))
+	assert.Error(t, err)
+	assert.IsType(t, &UnusedKeysError{}, err)
+	assert.Equal(t, []string{"services.web.deploy.resources.foo"}, err.(*UnusedKeysError).Keys)
+
+	_, err = loadYAML(sampleYAML)
+	assert.NoError(t, err)
+}
+
+func TestToYAMLPath(t *testing.T) {
+	networks := reflect.TypeOf(&map[string]types.NetworkConfig{})
+	assert.Equal(t, "front.ipam.config[0].foo", toYAMLPath(networks, "[front].Ipam.Config[0].foo"))
+
+	service := reflect.TypeOf(&types.ServiceConfig{})
+	assert.Equal(t, "deploy.update_config.foo", toYAMLPath(service, "Deploy.UpdateConfig.foo"))
+	assert.Equal(t, "build", toYAMLPath(service, "build"))
+}
+
+func durationPtr(value time.Duration) *time.Duration {
+	return &value
+}
+
+func strPtr(value string) *string {
+	return &value
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func uint64Ptr(value uint64) *uint64 {
+	return &value
+}
+
+func TestFullExample(t *testing.T) {
+	bytes, err := ioutil.ReadFile("full-example.yml")
+	assert.NoError(t, err)
+
+	config, err := loadYAML(string(bytes))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	workingDir, err := os.Getwd()
+	assert.NoError(t, err)
 
 	homeDir := os.Getenv("HOME")
 	stopGracePeriod := time.Duration(20 * time.Second)
@@ -569,6 +1268,15 @@ func TestFullExample(t *testing.T) {
 				FailureAction:   "continue",
 				Monitor:         time.Duration(60 * time.Second),
 				MaxFailureRatio: 0.3,
+				Order:           "start-first",
+			},
+			RollbackConfig: &types.UpdateConfig{
+				Parallelism:     uint64Ptr(2),
+				Delay:           time.Duration(5 * time.Second),
+				FailureAction:   "pause",
+				Monitor:         time.Duration(30 * time.Second),
+				MaxFailureRatio: 0.1,
+				Order:           "stop-first",
 			},
 			Resources: types.Resources{
 				Limits: &types.Resource{
@@ -587,7 +1295,9 @@ func TestFullExample(t *testing.T) {
 				Window:      durationPtr(2 * time.Minute),
 			},
 			Placement: types.Placement{
-				Constraints: []string{"node=foo"},
+				Constraints: []string{"node.hostname==foo"},
+				Preferences: []types.PlacementPreferences{{Spread: "node.labels.zone"}},
+				MaxReplicas: 5,
 			},
 		},
 		Devices:    []string{"/dev/ttyUSB0:/dev/ttyUSB0"},
@@ -595,13 +1305,13 @@ func TestFullExample(t *testing.T) {
 		DnsSearch:  []string{"dc1.example.com", "dc2.example.com"},
 		DomainName: "foo.com",
 		Entrypoint: []string{"/code/entrypoint.sh", "-p", "3000"},
-		Environment: map[string]string{
-			"RACK_ENV":       "development",
-			"SHOW":           "true",
-			"SESSION_SECRET": "",
-			"FOO":            "1",
-			"BAR":            "2",
-			"BAZ":            "3",
+		Environment: types.MappingWithEquals{
+			"RACK_ENV":       strPtr("development"),
+			"SHOW":           strPtr("true"),
+			"SESSION_SECRET": nil,
+			"FOO":            strPtr("1"),
+			"BAR":            strPtr("2"),
+			"BAZ":            strPtr("3"),
 		},
 		Expose: []string{"3000", "8000"},
 		ExternalLinks: []string{
@@ -650,8 +1360,11 @@ func TestFullExample(t *testing.T) {
 				Ipv6Address: "",
 			},
 			"other-network": {
-				Ipv4Address: "172.16.238.10",
-				Ipv6Address: "2001:3984:3989::10",
+				Ipv4Address:  "172.16.238.10",
+				Ipv6Address:  "2001:3984:3989::10",
+				LinkLocalIPs: []string{"169.254.8.8"},
+				MacAddress:   "02:42:ac:10:ee:0a",
+				Priority:     100,
 			},
 			"other-other-network": nil,
 		},
@@ -675,8 +1388,13 @@ func TestFullExample(t *testing.T) {
 		StdinOpen:       true,
 		StopSignal:      "SIGUSR1",
 		StopGracePeriod: &stopGracePeriod,
-		Tmpfs:           []string{"/run", "/tmp"},
-		Tty:             true,
+		Tmpfs: []types.TmpfsConfig{
+			{Target: "/run"},
+			{Target: "/tmp"},
+			{Target: "/var/cache", Size: 64 * 1024 * 1024, Mode: 01777},
+			{Target: "/var/run/app", Size: 10 * 1024 * 1024, Mode: 0755},
+		},
+		Tty: true,
 		Ulimits: map[string]*types.UlimitsConfig{
 			"nproc": {
 				Single: 65535,
@@ -704,7 +1422,10 @@ func TestFullExample(t *testing.T) {
 		"some-network": {},
 
 		"other-network": {
-			Driver: "overlay",
+			Driver:     "overlay",
+			Attachable: true,
+			Internal:   true,
+			EnableIPv6: true,
 			DriverOpts: map[string]string{
 				"foo": "bar",
 				"baz": "1",
@@ -712,9 +1433,18 @@ func TestFullExample(t *testing.T) {
 			Ipam: types.IPAMConfig{
 				Driver: "overlay",
 				Config: []*types.IPAMPool{
-					{Subnet: "172.16.238.0/24"},
-					{Subnet: "2001:3984:3989::/64"},
+					{
+						Subnet:       "172.16.238.0/24",
+						Gateway:      "172.16.238.1",
+						IPRange:      "172.16.238.128/25",
+						AuxAddresses: map[string]string{"host1": "172.16.238.5"},
+					},
+					{
+						Subnet:  "2001:3984:3989::/64",
+						Gateway: "2001:3984:3989::1",
+					},
 				},
+				Options: map[string]string{"foo": "bar"},
 			},
 		},
 
@@ -780,3 +1510,587 @@ type servicesByName []types.ServiceConfig
 func (sbn servicesByName) Len() int           { return len(sbn) }
 func (sbn servicesByName) Swap(i, j int)      { sbn[i], sbn[j] = sbn[j], sbn[i] }
 func (sbn servicesByName) Less(i, j int) bool { return sbn[i].Name < sbn[j].Name }
+
+func TestMarshalYAML(t *testing.T) {
+	source := `# top comment
+version: "3"
+services:
+  web:
+    image: nginx # the image
+    ports:
+      - 80:80
+      - "22:22"
+    stdin_open: true
+    healthcheck:
+      retries: 3
+      test: null
+`
+	node, err := ParseYAML([]byte(source))
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := MarshalYAML(node)
+	assert.NoError(t, err)
+	assert.Equal(t, source, string(out))
+}
+
+func TestLoadWithSkipInterpolationChecksResolvedValues(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3.8"
+services:
+  web:
+    image: ${REGISTRY}/web:${TAG}
+    tmpfs: ${TMPFS_TARGET}
+    deploy:
+      mode: ${MODE}
+      placement:
+        constraints: ["node.role == ${ROLE}"]
+      update_config:
+        order: ${ORDER}
+    networks:
+      front:
+        ipv4_address: ${ADDRESS}
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: ${SUBNET}
+`))
+	assert.NoError(t, err)
+
+	_, _, err = LoadWithOptions(context.Background(), buildConfigDetails(dict), WithSkipInterpolation())
+	assert.NoError(t, err)
+
+	for source, message := range map[string]string{
+		`
+version: "3.8"
+services:
+  web:
+    image: Nginx:1.11
+`: `Service web: invalid image "Nginx:1.11": invalid reference format: repository name must be lowercase`,
+		`
+version: "3.8"
+services:
+  web:
+    image: ${IMAGE}
+    tmpfs: run
+`: `Service web: tmpfs target "run" must be an absolute path`,
+		`
+version: "3.8"
+services:
+  web:
+    image: ${IMAGE}
+    ulimits:
+      nofile:
+        soft: 20000
+        hard: 10000
+`: `Service web: soft limit 20000 for ulimit nofile is greater than its hard limit 10000`,
+		`
+version: "3.8"
+services:
+  web:
+    image: ${IMAGE}
+    deploy:
+      mode: everywhere
+`: `Service web: deploy.mode must be one of replicated, global, got "everywhere"`,
+		`
+version: "3.8"
+services:
+  web:
+    image: ${IMAGE}
+    deploy:
+      placement:
+        preferences:
+          - spread: node.id
+`: `Service web: deploy.placement.preferences: invalid placement preference "node.id": spread must be a label under node.labels. or engine.labels.`,
+		`
+version: "3.8"
+services:
+  web:
+    image: ${IMAGE}
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          gateway: 10.0.1.1
+`: `Network front: gateway 10.0.1.1 is not within subnet 10.0.0.0/24`,
+	} {
+		dict, err := ParseYAML([]byte(source))
+		assert.NoError(t, err)
+
+		_, _, err = LoadWithOptions(context.Background(), buildConfigDetails(dict), WithSkipInterpolation())
+		assert.EqualError(t, err, message, source)
+	}
+}
+
+func TestLoadPlacementPreferences(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3.8"
+services:
+  web:
+    image: nginx
+    deploy:
+      placement:
+        preferences:
+          - spread: node.labels.zone
+          - spread: engine.labels.rack
+        max_replicas_per_node: 2
+`))
+	assert.NoError(t, err)
+
+	config, err := Load(buildConfigDetails(dict))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.Placement{
+		Preferences: []types.PlacementPreferences{
+			{Spread: "node.labels.zone"},
+			{Spread: "engine.labels.rack"},
+		},
+		MaxReplicas: 2,
+	}, config.Services[0].Deploy.Placement)
+}
+
+func TestLoadNetworkOptions(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3.8"
+services:
+  web:
+    image: nginx
+networks:
+  front:
+    name: shared-front
+    driver: overlay
+    attachable: true
+  outside:
+    external: true
+    name: corp-network
+`))
+	assert.NoError(t, err)
+	configDetails := buildConfigDetails(dict)
+
+	project, _, err := LoadProject(context.Background(), configDetails, WithProjectName("app"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "shared-front", project.NetworkName("front"))
+	assert.Equal(t, "corp-network", project.NetworkName("outside"))
+	assert.Equal(t, "app_default", project.NetworkName("default"))
+}
+
+func TestLoadMalformedUlimitsWithoutValidation(t *testing.T) {
+	testCases := map[string]string{
+		`
+      nofile: many
+`: `services.web.ulimits.nofile: a ulimit must be an integer or a mapping with soft and hard limits, got a string`,
+		`
+      nofile: 1.5
+`: `services.web.ulimits.nofile: a ulimit must be an integer or a mapping with soft and hard limits, got 1.5`,
+		`
+      nofile:
+        soft: 20000
+`: `services.web.ulimits.nofile: hard limit is missing`,
+		`
+      nofile:
+        soft: many
+        hard: 20000
+`: `services.web.ulimits.nofile: soft limit must be an integer, got a string`,
+		`
+      nofile:
+        soft: 1.5
+        hard: 20000
+`: `services.web.ulimits.nofile: soft limit must be an integer, got 1.5`,
+	}
+	for ulimits, message := range testCases {
+		dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    ulimits:` + ulimits))
+		assert.NoError(t, err)
+
+		_, _, err = LoadWithOptions(context.Background(), buildConfigDetails(dict), WithSkipValidation())
+		assert.EqualError(t, err, message)
+	}
+}
+
+func TestLoadUnlimitedUlimits(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    ulimits:
+      memlock:
+        soft: -1
+        hard: -1
+      nofile:
+        soft: 1024
+        hard: -1
+`))
+	assert.NoError(t, err)
+
+	config, err := Load(buildConfigDetails(dict))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &types.UlimitsConfig{Soft: -1, Hard: -1}, config.Services[0].Ulimits["memlock"])
+	assert.Equal(t, &types.UlimitsConfig{Soft: 1024, Hard: -1}, config.Services[0].Ulimits["nofile"])
+}
+
+func TestLoadNegativeReplicas(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: web
+    deploy:
+      replicas: -1
+`))
+	assert.NoError(t, err)
+
+	_, err = Load(buildConfigDetails(dict))
+	assert.EqualError(t, err, "services.web.deploy.replicas: -1 overflows uint")
+}
+
+func TestLoadMalformedWithoutValidation(t *testing.T) {
+	testCases := map[string]DecodeError{
+		`
+services:
+  web:
+    deploy: global
+`: {Path: "services.web.deploy", Message: "must be a mapping, got a string"},
+		`
+services:
+  web:
+    deploy:
+      resources:
+        limits:
+          memory: true
+`: {Path: "services.web.deploy.resources.limits.memory", Message: "a size must be a number or a string such as 64m, got a boolean"},
+		`
+services:
+  web:
+    labels: foo
+`: {Path: "services.web.labels", Message: "must be a mapping or a list, got a string"},
+		`
+services:
+  web:
+    environment: [1, 2]
+`: {Path: "services.web.environment", Message: "must be a mapping or a list of strings, got a number at index 0"},
+		`
+services:
+  web:
+    dns: {a: b}
+`: {Path: "services.web.dns", Message: "must be a string or a list of strings, got a mapping"},
+		`
+services:
+  web:
+    expose: "80"
+`: {Path: "services.web.expose", Message: "must be a list of strings or numbers, got a string"},
+		`
+networks:
+  front: [a]
+`: {Path: "networks.front", Message: "must be a mapping, got a list"},
+		`
+services:
+  web:
+    deploy:
+      replicas: -1
+`: {Path: "services.web.deploy.replicas", Message: "-1 overflows uint"},
+		`
+services:
+  web:
+    deploy:
+      placement:
+        max_replicas_per_node: -2
+`: {Path: "services.web.deploy.placement.max_replicas_per_node", Message: "-2 overflows uint"},
+		`
+services: [web]
+`: {Path: "services", Message: "must be a mapping, got a list"},
+		`
+services:
+  web: [a]
+`: {Path: "services.web", Message: "must be a mapping, got a list"},
+		`
+services:
+  web:
+`: {Path: "services.web", Message: "must be a mapping, got null"},
+		`
+networks: front
+`: {Path: "networks", Message: "must be a mapping, got a string"},
+		`
+volumes: [data]
+`: {Path: "volumes", Message: "must be a mapping, got a list"},
+	}
+	for source, expected := range testCases {
+		dict, err := ParseYAML([]byte(`version: "3.8"` + source))
+		assert.NoError(t, err)
+
+		_, _, err = LoadWithOptions(context.Background(), buildConfigDetails(dict), WithSkipValidation())
+		if decodeErr, ok := err.(*DecodeError); assert.True(t, ok, "%s: %#v", source, err) {
+			assert.Equal(t, expected, *decodeErr)
+		}
+	}
+}
+
+func TestLoadVolumeHostPaths(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - ./data:/data
+      - static/files:/static
+      - ../shared:/shared:ro
+      - ~:/root
+      - ~/configs:/etc/configs/:ro
+      - ~alice/keys:/keys
+      - datavolume:/var/lib/mysql
+      - /opt/data:/opt/data
+      - /var/lib/~cache
+`))
+	assert.NoError(t, err)
+	configDetails := types.ConfigDetails{
+		WorkingDir:  "/home/bob/project",
+		ConfigFiles: []types.ConfigFile{{Filename: "filename.yml", Config: dict}},
+		Environment: map[string]string{"HOME": "/home/bob"},
+	}
+	lookupHomeDir := func(name string) (string, error) {
+		if name == "alice" {
+			return "/home/alice", nil
+		}
+		return "", fmt.Errorf("user: unknown user %s", name)
+	}
+
+	config, _, err := LoadWithOptions(context.Background(), configDetails, WithLookupHomeDir(lookupHomeDir))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"/home/bob/project/data:/data",
+		"/home/bob/project/static/files:/static",
+		"/home/bob/shared:/shared:ro",
+		"/home/bob:/root",
+		"/home/bob/configs:/etc/configs/:ro",
+		"/home/alice/keys:/keys",
+		"datavolume:/var/lib/mysql",
+		"/opt/data:/opt/data",
+		"/var/lib/~cache",
+	}, config.Services[0].Volumes)
+}
+
+func TestLoadVolumeHostPathsHomeDirLookup(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - ~/configs:/etc/configs
+`))
+	assert.NoError(t, err)
+	configDetails := types.ConfigDetails{
+		WorkingDir:  "/home/bob/project",
+		ConfigFiles: []types.ConfigFile{{Filename: "filename.yml", Config: dict}},
+		Environment: map[string]string{},
+	}
+	var names []string
+	lookupHomeDir := func(name string) (string, error) {
+		names = append(names, name)
+		return "/home/current", nil
+	}
+
+	config, _, err := LoadWithOptions(context.Background(), configDetails, WithLookupHomeDir(lookupHomeDir))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{""}, names)
+	assert.Equal(t, []string{"/home/current/configs:/etc/configs"}, config.Services[0].Volumes)
+}
+
+func TestLoadVolumeHostPathsIgnoresHomeFromDotEnv(t *testing.T) {
+	workingDir, err := ioutil.TempDir("", "compose-file")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(workingDir)
+
+	err = ioutil.WriteFile(filepath.Join(workingDir, ".env"), []byte("HOME=/elsewhere\n"), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - ~/configs:/etc/configs
+`))
+	assert.NoError(t, err)
+	configDetails := buildConfigDetails(dict)
+	configDetails.WorkingDir = workingDir
+	configDetails.Environment = map[string]string{}
+	lookupHomeDir := func(name string) (string, error) {
+		return "/home/current", nil
+	}
+
+	config, _, err := LoadWithOptions(context.Background(), configDetails, WithLookupHomeDir(lookupHomeDir))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"/home/current/configs:/etc/configs"}, config.Services[0].Volumes)
+}
+
+func TestLoadVolumeHostPathsWithSkipInterpolation(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - ${DATA_DIR}/x:/data
+      - ~${USER_SUFFIX}/keys:/keys
+      - ${SOURCE:-./src}:/src:${MODE:-ro}
+      - ./static:${STATIC_TARGET}
+`))
+	assert.NoError(t, err)
+	configDetails := types.ConfigDetails{
+		WorkingDir:  "/work",
+		ConfigFiles: []types.ConfigFile{{Filename: "filename.yml", Config: dict}},
+		Environment: map[string]string{},
+	}
+
+	config, _, err := LoadWithOptions(context.Background(), configDetails, WithSkipInterpolation())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"${DATA_DIR}/x:/data",
+		"~${USER_SUFFIX}/keys:/keys",
+		"${SOURCE:-./src}:/src:${MODE:-ro}",
+		"/work/static:${STATIC_TARGET}",
+	}, config.Services[0].Volumes)
+}
+
+func TestLoadVolumeUnknownUser(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - ~nobody-here/keys:/keys
+`))
+	assert.NoError(t, err)
+	lookupHomeDir := func(name string) (string, error) {
+		return "", fmt.Errorf("user: unknown user %s", name)
+	}
+
+	_, _, err = LoadWithOptions(context.Background(), buildConfigDetails(dict), WithLookupHomeDir(lookupHomeDir))
+	assert.EqualError(t, err, `Service web: invalid volume "~nobody-here/keys:/keys": can not expand ~nobody-here: user: unknown user nobody-here`)
+}
+
+func TestLoadWindowsVolumePaths(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - C:\data:/data
+      - c:/logs:C:\logs:ro
+      - \\server\share:/share
+      - \\.\pipe\docker_engine:\\.\pipe\docker_engine
+      - .\config:C:\config
+      - datavolume:C:\data
+      - D:\cache
+`))
+	assert.NoError(t, err)
+	configDetails := types.ConfigDetails{
+		WorkingDir:  "/home/bob/project",
+		ConfigFiles: []types.ConfigFile{{Filename: "filename.yml", Config: dict}},
+		Environment: map[string]string{},
+	}
+
+	config, _, err := LoadWithOptions(context.Background(), configDetails)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		`C:\data:/data`,
+		`c:/logs:C:\logs:ro`,
+		`\\server\share:/share`,
+		`\\.\pipe\docker_engine:\\.\pipe\docker_engine`,
+		`/home/bob/project/config:C:\config`,
+		`datavolume:C:\data`,
+		`D:\cache`,
+	}, config.Services[0].Volumes)
+}
+
+func TestLoadTmpfs(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3.8"
+services:
+  web:
+    image: nginx
+    tmpfs: /run:size=1g,mode=0700
+    volumes:
+      - /data:/data
+      - type: tmpfs
+        target: /cache
+      - type: tmpfs
+        target: /scratch
+        tmpfs:
+          size: 1024
+          mode: "1777"
+      - type: tmpfs
+        target: /private
+        tmpfs:
+          mode: 0644
+      - type: tmpfs
+        target: /shared
+        tmpfs:
+          mode: 0o1777
+      - type: tmpfs
+        target: /owner
+        tmpfs:
+          mode: 0700
+`))
+	assert.NoError(t, err)
+
+	config, err := Load(buildConfigDetails(dict))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []types.TmpfsConfig{
+		{Target: "/run", Size: 1 << 30, Mode: 0700},
+		{Target: "/cache"},
+		{Target: "/scratch", Size: 1024, Mode: 01777},
+		{Target: "/private", Mode: 0644},
+		{Target: "/shared", Mode: 01777},
+		{Target: "/owner", Mode: 0700},
+	}, config.Services[0].Tmpfs)
+	assert.Equal(t, []string{"/data:/data"}, config.Services[0].Volumes)
+}
+
+func TestLoadInvalidTmpfsVolumeType(t *testing.T) {
+	dict, err := ParseYAML([]byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    volumes:
+      - type: bind
+        target: /data
+`))
+	assert.NoError(t, err)
+
+	_, err = Load(buildConfigDetails(dict))
+	assert.Error(t, err)
+}
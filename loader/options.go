@@ -0,0 +1,91 @@
+package loader
+
+import (
+	"github.com/aanand/compose-file/template"
+)
+
+// Options controls how LoadWithOptions loads a configuration
+type Options struct {
+	// SkipValidation skips validating the configuration against the schema
+	SkipValidation bool
+	// SkipInterpolation leaves variables in the configuration unsubstituted
+	SkipInterpolation bool
+	// SkipResolvePaths leaves relative and user-relative volume paths as they
+	// are written
+	SkipResolvePaths bool
+	// Strict returns an UnusedKeysError if the configuration contains keys
+	// which would be ignored
+	Strict bool
+	// LookupEnv resolves variables in place of ConfigDetails.Environment.
+	// Variables it does not resolve are still read from the .env file. If
+	// neither is set, variables are read from the process environment, or
+	// from the .env file only if the configuration is read from
+	// ConfigDetails.FS.
+	LookupEnv template.Mapping
+	// ProjectName is the name of the project the configuration belongs to. It
+	// is normalized, and defaults to the name of the working directory.
+	ProjectName string
+	// LookupHomeDir returns the home directory of the user called name, or of
+	// the current user if name is empty, to expand ~name and ~ in the host
+	// paths of volumes. The home directory of the current user is read from
+	// HOME first, which is never taken from the .env file. It defaults to
+	// looking users up on the system, unless the configuration is read from
+	// ConfigDetails.FS, in which case ~ is left unexpanded.
+	LookupHomeDir func(name string) (string, error)
+
+	// lookupShell reads variables from the environment the configuration is
+	// loaded in, without the .env file
+	lookupShell template.Mapping
+}
+
+// Option sets a field of Options
+type Option func(*Options)
+
+// WithSkipValidation skips validating the configuration against the schema
+func WithSkipValidation() Option {
+	return func(opts *Options) {
+		opts.SkipValidation = true
+	}
+}
+
+// WithSkipInterpolation leaves variables in the configuration unsubstituted
+func WithSkipInterpolation() Option {
+	return func(opts *Options) {
+		opts.SkipInterpolation = true
+	}
+}
+
+// WithSkipResolvePaths leaves volume paths as they are written
+func WithSkipResolvePaths() Option {
+	return func(opts *Options) {
+		opts.SkipResolvePaths = true
+	}
+}
+
+// WithStrict makes keys which would be ignored an error
+func WithStrict() Option {
+	return func(opts *Options) {
+		opts.Strict = true
+	}
+}
+
+// WithLookupEnv resolves variables with lookup
+func WithLookupEnv(lookup template.Mapping) Option {
+	return func(opts *Options) {
+		opts.LookupEnv = lookup
+	}
+}
+
+// WithProjectName sets the name of the project
+func WithProjectName(name string) Option {
+	return func(opts *Options) {
+		opts.ProjectName = name
+	}
+}
+
+// WithLookupHomeDir looks up the home directories of users with lookup
+func WithLookupHomeDir(lookup func(name string) (string, error)) Option {
+	return func(opts *Options) {
+		opts.LookupHomeDir = lookup
+	}
+}
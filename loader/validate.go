@@ -0,0 +1,432 @@
+package loader
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aanand/compose-file/types"
+)
+
+var (
+	deployModes            = []string{"replicated", "global"}
+	updateFailureActions   = []string{"continue", "pause", "rollback"}
+	rollbackFailureActions = []string{"continue", "pause"}
+	updateOrders           = []string{"stop-first", "start-first"}
+)
+
+// validateService checks the values of a loaded service which the schema can
+// not check. If interpolation was skipped, values which still contain
+// variables are not checked.
+func validateService(service *types.ServiceConfig, opts *Options) error {
+	if service.Image != "" && !isUnresolved(opts, service.Image) {
+		if _, err := types.ParseImageReference(service.Image); err != nil {
+			return fmt.Errorf("Service %s: invalid image %q: %s", service.Name, service.Image, err)
+		}
+	}
+
+	for _, constraint := range service.Deploy.Placement.Constraints {
+		if isUnresolved(opts, constraint) {
+			continue
+		}
+		if _, err := types.ParsePlacementConstraint(constraint); err != nil {
+			return fmt.Errorf("Service %s: deploy.placement.constraints: %s", service.Name, err)
+		}
+	}
+	for _, preference := range service.Deploy.Placement.Preferences {
+		if isUnresolved(opts, preference.Spread) {
+			continue
+		}
+		if err := preference.Validate(); err != nil {
+			return fmt.Errorf("Service %s: deploy.placement.preferences: %s", service.Name, err)
+		}
+	}
+
+	if err := validateServiceNetworks(service, opts); err != nil {
+		return err
+	}
+	if err := validateUlimits(service); err != nil {
+		return err
+	}
+	if err := validateTmpfs(service, opts); err != nil {
+		return err
+	}
+	return validateDeploy(service, opts)
+}
+
+// isUnresolved returns true if interpolation was skipped and value contains a
+// variable, so that it can not be checked yet
+func isUnresolved(opts *Options, value string) bool {
+	return opts.SkipInterpolation && strings.Contains(value, "$")
+}
+
+// validateTmpfs checks that tmpfs mounts have absolute targets, and that no
+// two tmpfs mounts or volumes share a target
+func validateTmpfs(service *types.ServiceConfig, opts *Options) error {
+	mounts := map[string]string{}
+	for _, volume := range service.Volumes {
+		if isUnresolved(opts, volume) {
+			continue
+		}
+		if parsed, err := types.ParseVolumeSpec(volume); err == nil {
+			mounts[path.Clean(parsed.Target)] = "volume " + volume
+		}
+	}
+	for _, tmpfs := range service.Tmpfs {
+		if isUnresolved(opts, tmpfs.Target) {
+			continue
+		}
+		if !path.IsAbs(tmpfs.Target) {
+			return fmt.Errorf("Service %s: tmpfs target %q must be an absolute path", service.Name, tmpfs.Target)
+		}
+		target := path.Clean(tmpfs.Target)
+		if other, ok := mounts[target]; ok {
+			return fmt.Errorf("Service %s: tmpfs %s conflicts with %s", service.Name, tmpfs.Target, other)
+		}
+		mounts[target] = "tmpfs " + tmpfs.Target
+	}
+	return nil
+}
+
+func validateUlimits(service *types.ServiceConfig) error {
+	var names []string
+	for name := range service.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ulimit := service.Ulimits[name]
+		if ulimit == nil {
+			continue
+		}
+		if err := ulimit.Validate(name); err != nil {
+			return fmt.Errorf("Service %s: %s", service.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateServiceNetworks(service *types.ServiceConfig, opts *Options) error {
+	var names []string
+	for name := range service.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		network := service.Networks[name]
+		if network == nil {
+			continue
+		}
+		for _, address := range network.LinkLocalIPs {
+			if isUnresolved(opts, address) {
+				continue
+			}
+			if ip := net.ParseIP(address); ip == nil || !ip.IsLinkLocalUnicast() {
+				return fmt.Errorf("Service %s: invalid networks.%s.link_local_ips %q, expected a link-local IP address", service.Name, name, address)
+			}
+		}
+		if network.MacAddress != "" && !isUnresolved(opts, network.MacAddress) {
+			if _, err := net.ParseMAC(network.MacAddress); err != nil {
+				return fmt.Errorf("Service %s: invalid networks.%s.mac_address %q", service.Name, name, network.MacAddress)
+			}
+		}
+	}
+	return nil
+}
+
+func validateDeploy(service *types.ServiceConfig, opts *Options) error {
+	deploy := service.Deploy
+	if isUnresolved(opts, deploy.Mode) {
+		return nil
+	}
+	if deploy.Mode != "" && !types.ContainsString(deployModes, deploy.Mode) {
+		return invalidValueError(service, "deploy.mode", deploy.Mode, deployModes)
+	}
+	if deploy.Mode == "global" {
+		if deploy.Replicas != nil {
+			return fmt.Errorf("Service %s: deploy.replicas can only be used in replicated mode", service.Name)
+		}
+		if deploy.Placement.MaxReplicas > 0 {
+			return fmt.Errorf("Service %s: deploy.placement.max_replicas_per_node can only be used in replicated mode", service.Name)
+		}
+	}
+
+	if err := validateUpdateConfig(service, "deploy.update_config", deploy.UpdateConfig, updateFailureActions, opts); err != nil {
+		return err
+	}
+	return validateUpdateConfig(service, "deploy.rollback_config", deploy.RollbackConfig, rollbackFailureActions, opts)
+}
+
+func validateUpdateConfig(service *types.ServiceConfig, path string, config *types.UpdateConfig, failureActions []string, opts *Options) error {
+	if config == nil {
+		return nil
+	}
+	if config.FailureAction != "" && !isUnresolved(opts, config.FailureAction) &&
+		!types.ContainsString(failureActions, config.FailureAction) {
+		return invalidValueError(service, path+".failure_action", config.FailureAction, failureActions)
+	}
+	if config.Order != "" && !isUnresolved(opts, config.Order) && !types.ContainsString(updateOrders, config.Order) {
+		return invalidValueError(service, path+".order", config.Order, updateOrders)
+	}
+	return nil
+}
+
+func invalidValueError(service *types.ServiceConfig, path string, value string, allowed []string) error {
+	return fmt.Errorf("Service %s: %s must be one of %s, got %q", service.Name, path, strings.Join(allowed, ", "), value)
+}
+
+// validateConfig checks the values of the loaded configuration which relate
+// resources to each other. Like validateService, it skips values which still
+// contain variables if interpolation was skipped.
+func validateConfig(config *types.Config, opts *Options) error {
+	if err := validateNetworks(config.Networks, opts); err != nil {
+		return err
+	}
+	return validateStaticAddresses(config, opts)
+}
+
+type networkSubnet struct {
+	network string
+	subnet  *net.IPNet
+}
+
+// validateNetworks checks the options of every network, the IPAM
+// configuration of those which are not external, and that no two subnets
+// overlap
+func validateNetworks(networks map[string]types.NetworkConfig, opts *Options) error {
+	var names []string
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var subnets []networkSubnet
+	for _, name := range names {
+		network := networks[name]
+		if err := validateNetworkOptions(network, opts); err != nil {
+			return fmt.Errorf("Network %s: %s", name, err)
+		}
+		if network.External.External {
+			continue
+		}
+		for _, pool := range network.Ipam.Config {
+			if pool == nil || hasUnresolvedAddress(opts, pool) {
+				continue
+			}
+			subnet, err := validateIPAMPool(pool)
+			if err != nil {
+				return fmt.Errorf("Network %s: %s", name, err)
+			}
+			if subnet == nil {
+				continue
+			}
+			for _, other := range subnets {
+				if other.subnet.Contains(subnet.IP) || subnet.Contains(other.subnet.IP) {
+					return fmt.Errorf("Network %s: subnet %s overlaps with subnet %s of network %s",
+						name, subnet, other.subnet, other.network)
+				}
+			}
+			subnets = append(subnets, networkSubnet{network: name, subnet: subnet})
+		}
+	}
+	return nil
+}
+
+// validateNetworkOptions checks the options of a network which depend on each
+// other
+func validateNetworkOptions(network types.NetworkConfig, opts *Options) error {
+	if network.External.External {
+		var set []string
+		for option, isSet := range map[string]bool{
+			"driver":      network.Driver != "",
+			"driver_opts": len(network.DriverOpts) > 0,
+			"ipam":        network.Ipam.Driver != "" || len(network.Ipam.Config) > 0 || len(network.Ipam.Options) > 0,
+			"attachable":  network.Attachable,
+			"internal":    network.Internal,
+			"enable_ipv6": network.EnableIPv6,
+		} {
+			if isSet {
+				set = append(set, option)
+			}
+		}
+		if len(set) > 0 {
+			sort.Strings(set)
+			return fmt.Errorf("external networks can not set %s", strings.Join(set, ", "))
+		}
+		if network.Name != "" && network.Name != network.External.Name &&
+			!isUnresolved(opts, network.Name) && !isUnresolved(opts, network.External.Name) {
+			return fmt.Errorf("name %q and external.name %q must be the same", network.Name, network.External.Name)
+		}
+	}
+	if network.Attachable && network.Driver != "" && network.Driver != "overlay" && !isUnresolved(opts, network.Driver) {
+		return fmt.Errorf("attachable can only be used with the overlay driver, not %s", network.Driver)
+	}
+	return nil
+}
+
+// hasUnresolvedAddress returns true if any address of pool still contains a
+// variable
+func hasUnresolvedAddress(opts *Options, pool *types.IPAMPool) bool {
+	if isUnresolved(opts, pool.Subnet) || isUnresolved(opts, pool.Gateway) || isUnresolved(opts, pool.IPRange) {
+		return true
+	}
+	for _, address := range pool.AuxAddresses {
+		if isUnresolved(opts, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIPAMPool checks that the addresses of pool are valid and within its
+// subnet, and returns the subnet, which is nil if the pool has none
+func validateIPAMPool(pool *types.IPAMPool) (*net.IPNet, error) {
+	if pool.Subnet == "" {
+		if pool.Gateway != "" || pool.IPRange != "" || len(pool.AuxAddresses) > 0 {
+			return nil, fmt.Errorf("ipam.config must have a subnet to set gateway, ip_range or aux_addresses")
+		}
+		return nil, nil
+	}
+	_, subnet, err := net.ParseCIDR(pool.Subnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet %q, expected a CIDR such as 10.0.0.0/24", pool.Subnet)
+	}
+
+	if pool.Gateway != "" {
+		if err := validateAddressInSubnet("gateway", pool.Gateway, subnet); err != nil {
+			return nil, err
+		}
+	}
+
+	if pool.IPRange != "" {
+		_, ipRange, err := net.ParseCIDR(pool.IPRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip_range %q, expected a CIDR such as 10.0.0.0/25", pool.IPRange)
+		}
+		rangeOnes, rangeBits := ipRange.Mask.Size()
+		subnetOnes, subnetBits := subnet.Mask.Size()
+		if !subnet.Contains(ipRange.IP) || rangeBits != subnetBits || rangeOnes < subnetOnes {
+			return nil, fmt.Errorf("ip_range %s is not within subnet %s", pool.IPRange, subnet)
+		}
+	}
+
+	var hosts []string
+	for host := range pool.AuxAddresses {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if err := validateAddressInSubnet("aux_addresses."+host, pool.AuxAddresses[host], subnet); err != nil {
+			return nil, err
+		}
+	}
+	return subnet, nil
+}
+
+func validateAddressInSubnet(field string, address string, subnet *net.IPNet) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("invalid %s %q, expected an IP address", field, address)
+	}
+	if !subnet.Contains(ip) {
+		return fmt.Errorf("%s %s is not within subnet %s", field, address, subnet)
+	}
+	return nil
+}
+
+// validateStaticAddresses checks that the static IP addresses of services are
+// of the right family, are within a subnet of their network, and are not used
+// twice. Addresses on external networks are not checked, since their subnets
+// are unknown, while networks which are not declared, such as the implicit
+// default network, have no user-defined subnet.
+func validateStaticAddresses(config *types.Config, opts *Options) error {
+	used := map[string]string{}
+	for _, service := range config.Services {
+		var names []string
+		for name := range service.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			serviceNetwork := service.Networks[name]
+			network := config.Networks[name]
+			if serviceNetwork == nil || network.External.External || hasUnresolvedSubnet(opts, network) {
+				continue
+			}
+			addresses := []struct {
+				field   string
+				address string
+				ipv4    bool
+			}{
+				{"ipv4_address", serviceNetwork.Ipv4Address, true},
+				{"ipv6_address", serviceNetwork.Ipv6Address, false},
+			}
+			for _, address := range addresses {
+				if address.address == "" || isUnresolved(opts, address.address) {
+					continue
+				}
+				field := fmt.Sprintf("networks.%s.%s", name, address.field)
+				ip, err := validateStaticAddress(field, address.address, address.ipv4, name, network)
+				if err != nil {
+					return fmt.Errorf("Service %s: %s", service.Name, err)
+				}
+				key := name + "/" + ip.String()
+				if other, ok := used[key]; ok {
+					return fmt.Errorf("Service %s: %s %s is already used by %s", service.Name, field, address.address, other)
+				}
+				used[key] = "service " + service.Name
+			}
+		}
+	}
+	return nil
+}
+
+// hasUnresolvedSubnet returns true if the subnet of any IPAM pool of network
+// still contains a variable
+func hasUnresolvedSubnet(opts *Options, network types.NetworkConfig) bool {
+	for _, pool := range network.Ipam.Config {
+		if pool != nil && isUnresolved(opts, pool.Subnet) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateStaticAddress(field string, address string, ipv4 bool, networkName string, network types.NetworkConfig) (net.IP, error) {
+	family := "IPv6"
+	if ipv4 {
+		family = "IPv4"
+	}
+	ip := net.ParseIP(address)
+	if ip == nil || (ip.To4() != nil) != ipv4 {
+		return nil, fmt.Errorf("invalid %s %q, expected an %s address", field, address, family)
+	}
+
+	hasSubnet := false
+	for _, pool := range network.Ipam.Config {
+		if pool == nil || pool.Subnet == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(pool.Subnet)
+		if err != nil || (subnet.IP.To4() != nil) != ipv4 {
+			continue
+		}
+		hasSubnet = true
+		if !subnet.Contains(ip) {
+			continue
+		}
+		if pool.Gateway != "" && net.ParseIP(pool.Gateway).Equal(ip) {
+			return nil, fmt.Errorf("%s %s is the gateway of network %s", field, address, networkName)
+		}
+		return ip, nil
+	}
+	if !hasSubnet {
+		return nil, fmt.Errorf("%s is set, but network %s has no user-defined %s subnet", field, networkName, family)
+	}
+	return nil, fmt.Errorf("%s %s is not within a subnet of network %s", field, address, networkName)
+}
@@ -0,0 +1,526 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/aanand/compose-file/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// validationFixture is the configuration the source of each validation test
+// case is merged into: the service web, the network front with a subnet and a
+// gateway, and the network back without any
+const validationFixture = `
+version: "3.8"
+services:
+  web:
+    image: nginx
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          gateway: 10.0.0.1
+  back: {}
+`
+
+// loadWithFixture loads source as an override file of validationFixture
+func loadWithFixture(t *testing.T, source string) error {
+	fixture, err := ParseYAML([]byte(validationFixture))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	override, err := ParseYAML([]byte(source))
+	if !assert.NoError(t, err, source) {
+		t.FailNow()
+	}
+	configDetails := buildConfigDetails(fixture)
+	configDetails.ConfigFiles = append(configDetails.ConfigFiles, types.ConfigFile{
+		Filename: "override.yml",
+		Config:   override,
+	})
+	_, err = Load(configDetails)
+	return err
+}
+
+// TestValidate checks each rule the loader validates beyond the schema, with a
+// valid source first and then the errors it reports. An empty message means the
+// source is valid.
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		source  string
+		message string
+	}{
+		// image
+		{source: `
+services:
+  web:
+    image: registry:5000/nginx:1.11
+`},
+		{source: `
+services:
+  web:
+    image: Nginx:1.11
+`, message: `Service web: invalid image "Nginx:1.11": invalid reference format: repository name must be lowercase`},
+
+		// deploy.placement.constraints
+		{source: `
+services:
+  web:
+    deploy:
+      placement:
+        constraints: [node.role==manager, node.labels.zone!=east]
+`},
+		{source: `
+services:
+  web:
+    deploy:
+      placement:
+        constraints: [node.role==manager, node.rol==manager]
+`, message: `Service web: deploy.placement.constraints: invalid constraint "node.rol==manager": unknown key "node.rol", expected one of node.id, node.hostname, node.role, node.platform.os, node.platform.arch, or a label under node.labels. or engine.labels.`},
+
+		// deploy.placement.preferences and max_replicas_per_node
+		{source: `
+services:
+  web:
+    deploy:
+      placement:
+        preferences:
+          - spread: node.labels.zone
+        max_replicas_per_node: 1
+`},
+		{source: `
+services:
+  web:
+    deploy:
+      placement:
+        preferences:
+          - spread: node.zone
+`, message: `Service web: deploy.placement.preferences: invalid placement preference "node.zone": spread must be a label under node.labels. or engine.labels.`},
+		{source: `
+services:
+  web:
+    deploy:
+      placement:
+        preferences:
+          - zone: node.labels.zone
+`, message: `services.web.deploy.placement.preferences.0 spread is required`},
+		{source: `
+services:
+  web:
+    deploy:
+      mode: global
+      placement:
+        max_replicas_per_node: 1
+`, message: `Service web: deploy.placement.max_replicas_per_node can only be used in replicated mode`},
+
+		// deploy.mode and replicas
+		{source: `
+services:
+  web:
+    deploy:
+      mode: global
+`},
+		{source: `
+services:
+  web:
+    deploy:
+      mode: replicate
+`, message: `Service web: deploy.mode must be one of replicated, global, got "replicate"`},
+		{source: `
+services:
+  web:
+    deploy:
+      mode: global
+      replicas: 2
+`, message: `Service web: deploy.replicas can only be used in replicated mode`},
+
+		// deploy.update_config and rollback_config
+		{source: `
+services:
+  web:
+    deploy:
+      update_config:
+        failure_action: rollback
+        order: start-first
+      rollback_config:
+        failure_action: pause
+`},
+		{source: `
+services:
+  web:
+    deploy:
+      update_config:
+        failure_action: restart
+`, message: `Service web: deploy.update_config.failure_action must be one of continue, pause, rollback, got "restart"`},
+		{source: `
+services:
+  web:
+    deploy:
+      rollback_config:
+        failure_action: rollback
+`, message: `Service web: deploy.rollback_config.failure_action must be one of continue, pause, got "rollback"`},
+		{source: `
+services:
+  web:
+    deploy:
+      update_config:
+        order: random
+`, message: `Service web: deploy.update_config.order must be one of stop-first, start-first, got "random"`},
+
+		// networks.*.ipam
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          gateway: 10.0.0.1
+          ip_range: 10.0.0.128/25
+          aux_addresses:
+            router: 10.0.0.2
+  back:
+    ipam:
+      config:
+        - subnet: 10.1.0.0/16
+`},
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/33
+`, message: `Network front: invalid subnet "10.0.0.0/33", expected a CIDR such as 10.0.0.0/24`},
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          gateway: 10.0.1.1
+`, message: `Network front: gateway 10.0.1.1 is not within subnet 10.0.0.0/24`},
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          gateway: gateway
+`, message: `Network front: invalid gateway "gateway", expected an IP address`},
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          ip_range: 10.0.0.0/16
+`, message: `Network front: ip_range 10.0.0.0/16 is not within subnet 10.0.0.0/24`},
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+          aux_addresses:
+            router: 10.1.0.1
+`, message: `Network front: aux_addresses.router 10.1.0.1 is not within subnet 10.0.0.0/24`},
+		{source: `
+networks:
+  front:
+    ipam:
+      config:
+        - gateway: 10.0.0.1
+`, message: `Network front: ipam.config must have a subnet to set gateway, ip_range or aux_addresses`},
+		{source: `
+networks:
+  back:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/16
+`, message: `Network front: subnet 10.0.0.0/24 overlaps with subnet 10.0.0.0/16 of network back`},
+
+		// services.*.networks.*.ipv4_address and ipv6_address
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv4_address: 10.0.0.10
+        ipv6_address: fd00::10
+      outside:
+        ipv4_address: 192.168.1.10
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.0.0.0/24
+        - subnet: fd00::/64
+  outside:
+    external: true
+`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv4_address: 10.0.1.5
+`, message: `Service web: networks.front.ipv4_address 10.0.1.5 is not within a subnet of network front`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv4_address: fd00::5
+`, message: `Service web: invalid networks.front.ipv4_address "fd00::5", expected an IPv4 address`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv6_address: 10.0.0.5
+`, message: `Service web: invalid networks.front.ipv6_address "10.0.0.5", expected an IPv6 address`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv6_address: fd00::5
+`, message: `Service web: networks.front.ipv6_address is set, but network front has no user-defined IPv6 subnet`},
+		{source: `
+services:
+  web:
+    networks:
+      back:
+        ipv4_address: 10.0.0.5
+`, message: `Service web: networks.back.ipv4_address is set, but network back has no user-defined IPv4 subnet`},
+		{source: `
+services:
+  web:
+    networks:
+      default:
+        ipv4_address: 10.0.0.5
+`, message: `Service web: networks.default.ipv4_address is set, but network default has no user-defined IPv4 subnet`},
+		{source: `
+services:
+  web:
+    networks:
+      undeclared:
+        ipv6_address: fd00::5
+`, message: `Service web: networks.undeclared.ipv6_address is set, but network undeclared has no user-defined IPv6 subnet`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv4_address: 10.0.0.1
+`, message: `Service web: networks.front.ipv4_address 10.0.0.1 is the gateway of network front`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        ipv4_address: 10.0.0.20
+  db:
+    image: postgres
+    networks:
+      front:
+        ipv4_address: 10.0.0.20
+`, message: `Service db: networks.front.ipv4_address 10.0.0.20 is already used by service web`},
+
+		// services.*.networks.*.link_local_ips and mac_address
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        link_local_ips: [169.254.8.8]
+        mac_address: 02:42:ac:11:00:02
+`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        link_local_ips: [10.0.0.1]
+`, message: `Service web: invalid networks.front.link_local_ips "10.0.0.1", expected a link-local IP address`},
+		{source: `
+services:
+  web:
+    networks:
+      front:
+        mac_address: not-a-mac
+`, message: `Service web: invalid networks.front.mac_address "not-a-mac"`},
+
+		// networks.*.attachable, external and name
+		{source: `
+networks:
+  front:
+    driver: overlay
+    attachable: true
+  outside:
+    external: true
+    name: corp-network
+`},
+		{source: `
+networks:
+  front:
+    driver: bridge
+    attachable: true
+`, message: `Network front: attachable can only be used with the overlay driver, not bridge`},
+		{source: `
+networks:
+  outside:
+    external: true
+    driver: overlay
+    internal: true
+`, message: `Network outside: external networks can not set driver, internal`},
+		{source: `
+networks:
+  outside:
+    name: one
+    external:
+      name: two
+`, message: `Network outside: name "one" and external.name "two" must be the same`},
+
+		// services.*.ulimits
+		{source: `
+services:
+  web:
+    ulimits:
+      nproc: 65535
+      nofile:
+        soft: 20000
+        hard: 40000
+      memlock:
+        soft: -1
+        hard: -1
+`},
+		{source: `
+services:
+  web:
+    ulimits:
+      nofiles: 1024
+`, message: `Service web: unknown ulimit "nofiles", expected one of core, cpu, data, fsize, locks, memlock, msgqueue, nice, nofile, nproc, rss, rtprio, rttime, sigpending, stack`},
+		{source: `
+services:
+  web:
+    ulimits:
+      nofile:
+        soft: 40000
+        hard: 20000
+`, message: `Service web: soft limit 40000 for ulimit nofile is greater than its hard limit 20000`},
+		{source: `
+services:
+  web:
+    ulimits:
+      memlock:
+        soft: -1
+        hard: 1024
+`, message: `Service web: soft limit -1 for ulimit memlock is greater than its hard limit 1024`},
+		{source: `
+services:
+  web:
+    ulimits:
+      nproc: -2
+`, message: `Service web: invalid soft limit -2 for ulimit nproc, expected -1 for unlimited or a non-negative number`},
+
+		// services.*.tmpfs and tmpfs volumes
+		{source: `
+services:
+  web:
+    tmpfs: /run:size=1g,mode=0700
+    volumes:
+      - /data:/data
+      - type: tmpfs
+        target: /cache
+        tmpfs:
+          mode: "1777"
+`},
+		{source: `
+services:
+  web:
+    tmpfs: /run:noexec
+`, message: `services.web.tmpfs[0]: invalid tmpfs option "noexec" in "/run:noexec", expected size=<size> or mode=<mode>`},
+		{source: `
+services:
+  web:
+    tmpfs: /run:uid=1000
+`, message: `services.web.tmpfs[0]: unsupported tmpfs option "uid" in "/run:uid=1000", only size and mode are supported`},
+		{source: `
+services:
+  web:
+    tmpfs: /run:size=lots
+`, message: `services.web.tmpfs[0]: invalid tmpfs size in "/run:size=lots": invalid size: 'lots'`},
+		{source: `
+services:
+  web:
+    tmpfs: /run:mode=999
+`, message: `services.web.tmpfs[0]: invalid tmpfs mode 999, expected an octal number such as 1777`},
+		{source: `
+services:
+  web:
+    tmpfs: run
+`, message: `Service web: tmpfs target "run" must be an absolute path`},
+		{source: `
+services:
+  web:
+    tmpfs: [/run, /run/]
+`, message: `Service web: tmpfs /run/ conflicts with tmpfs /run`},
+		{source: `
+services:
+  web:
+    tmpfs: /data
+    volumes:
+      - ./data:/data/
+`, message: `Service web: tmpfs /data conflicts with volume ./data:/data/`},
+		{source: `
+services:
+  web:
+    volumes:
+      - /data
+      - type: tmpfs
+        target: /data
+        tmpfs:
+          mode: 010000
+`, message: `services.web.tmpfs[0]: invalid tmpfs mode 010000, expected an octal number such as 1777`},
+		{source: `
+services:
+  web:
+    volumes:
+      - type: tmpfs
+        target: /data
+        tmpfs:
+          mode: "1778"
+`, message: `services.web.tmpfs[0]: invalid tmpfs mode 1778, expected an octal number such as 1777`},
+		{source: `
+services:
+  web:
+    volumes:
+      - /data
+      - type: tmpfs
+        target: /data
+`, message: `Service web: tmpfs /data conflicts with volume /data`},
+
+		// services.*.volumes
+		{source: `
+services:
+  web:
+    volumes:
+      - /a:/b:ro
+`},
+		{source: `
+services:
+  web:
+    volumes:
+      - /a:/b:ro:nocopy
+`, message: `Service web: invalid volume "/a:/b:ro:nocopy": too many colons`},
+	}
+	for _, testCase := range testCases {
+		err := loadWithFixture(t, testCase.source)
+		if testCase.message == "" {
+			assert.NoError(t, err, testCase.source)
+		} else {
+			assert.EqualError(t, err, testCase.message, testCase.source)
+		}
+	}
+}
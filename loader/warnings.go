@@ -0,0 +1,312 @@
+package loader
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aanand/compose-file/types"
+)
+
+// serviceKeysLoadedSeparately are service keys which have no field in
+// types.ServiceConfig, because the loader reads them directly
+var serviceKeysLoadedSeparately = []string{"env_file"}
+
+// pathSegmentRegexp matches the keys and [index] segments of a path such as
+// services.web.ports[0] or [front].Ipam.Config[0]
+var pathSegmentRegexp = regexp.MustCompile(`[^.\[\]]+|\[([^\]]*)\]`)
+
+// WarningKind classifies a Warning
+type WarningKind string
+
+const (
+	// WarningUnsupported is a property which is valid Compose but is not
+	// supported by the deployment target
+	WarningUnsupported WarningKind = "unsupported"
+	// WarningDeprecated is a property which still works but should no longer
+	// be used
+	WarningDeprecated WarningKind = "deprecated"
+	// WarningIgnored is a key which was accepted by the schema but has no
+	// effect on the loaded configuration
+	WarningIgnored WarningKind = "ignored"
+	// WarningUnset is a variable which should be passed through from the
+	// environment, but is not set there
+	WarningUnset WarningKind = "unset"
+)
+
+var sectionNames = map[string]string{
+	"services": "service",
+	"networks": "network",
+	"volumes":  "volume",
+}
+
+// Warning describes a part of the configuration which was accepted, but may
+// not behave as expected
+type Warning struct {
+	Kind WarningKind
+	// Section is the kind of resource the property belongs to: service,
+	// network or volume
+	Section string
+	// Name is the name of the service, network or volume
+	Name string
+	// Property is the path of the property within the resource, for example
+	// deploy.resources.foo
+	Property string
+	// Message explains the warning and how to fix it
+	Message  string
+	Position types.Position
+}
+
+// Path returns the full path of the property, for example
+// services.web.deploy.resources.foo
+func (w Warning) Path() string {
+	for key, section := range sectionNames {
+		if section == w.Section {
+			return strings.Join([]string{key, w.Name, w.Property}, ".")
+		}
+	}
+	return w.Property
+}
+
+func (w Warning) String() string {
+	if w.Position.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", w.Position, w.Path(), w.Message)
+	}
+	return fmt.Sprintf("%s: %s", w.Path(), w.Message)
+}
+
+// UnusedKeysError is returned by LoadStrict when the configuration contains
+// keys which would be ignored
+type UnusedKeysError struct {
+	Keys []string
+}
+
+func (e *UnusedKeysError) Error() string {
+	return fmt.Sprintf("Configuration contains unused keys: %s", strings.Join(e.Keys, ", "))
+}
+
+// unsupportedProperties and deprecatedProperties list the properties which
+// are reported as warnings, by section
+var (
+	unsupportedProperties = map[string][]string{
+		"service": types.UnsupportedProperties,
+	}
+	deprecatedProperties = map[string]map[string]string{
+		"service": types.DeprecatedProperties,
+		"network": types.DeprecatedNetworkProperties,
+		"volume":  types.DeprecatedVolumeProperties,
+	}
+)
+
+// getWarnings returns a warning for every unsupported or deprecated property
+// of a service, network or volume in config, followed by a warning for every
+// unused key which was not already reported
+func getWarnings(config *types.Node, unused []string) []Warning {
+	var warnings []Warning
+	reported := map[string]bool{}
+
+	for _, key := range []string{"services", "networks", "volumes"} {
+		resources := config.Lookup(key)
+		if resources == nil {
+			continue
+		}
+		for _, resource := range resources.Items {
+			warnings = append(warnings, getPropertyWarnings(sectionNames[key], resource.Key, "", resource.Value)...)
+		}
+	}
+	for _, warning := range warnings {
+		reported[warning.Path()] = true
+	}
+
+	for _, key := range unused {
+		if reported[key] {
+			continue
+		}
+		parts := strings.SplitN(key, ".", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Kind:     WarningIgnored,
+			Section:  sectionNames[parts[0]],
+			Name:     parts[1],
+			Property: parts[2],
+			Message:  "unknown key, it will be ignored",
+			Position: keyPosition(config, key),
+		})
+	}
+	return warnings
+}
+
+// getPropertyWarnings returns the warnings for the properties of the resource
+// name below node, where prefix is the path of node within the resource
+func getPropertyWarnings(section, name, prefix string, node *types.Node) []Warning {
+	if node == nil {
+		return nil
+	}
+	var warnings []Warning
+	for _, item := range node.Items {
+		property := prefix + item.Key
+		warning := Warning{
+			Section:  section,
+			Name:     name,
+			Property: property,
+			Position: item.KeyPosition,
+		}
+		if types.ContainsString(unsupportedProperties[section], property) {
+			warning.Kind = WarningUnsupported
+			warning.Message = unsupportedMessage(property)
+		} else if message, ok := deprecatedProperties[section][property]; ok {
+			warning.Kind = WarningDeprecated
+			warning.Message = message
+		} else {
+			warnings = append(warnings, getPropertyWarnings(section, name, property+".", item.Value)...)
+			continue
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+func unsupportedMessage(property string) string {
+	message := fmt.Sprintf("%s is not supported and will be ignored.", property)
+	if hint, ok := types.UnsupportedPropertyHints[property]; ok {
+		message += " " + hint
+	}
+	return message
+}
+
+// getUnsetVariableWarnings returns a warning for every environment variable of
+// a service which was given without a value and could not be resolved
+func getUnsetVariableWarnings(config *types.Node, services []types.ServiceConfig) []Warning {
+	var warnings []Warning
+	for _, service := range services {
+		var names []string
+		for name, value := range service.Environment {
+			if value == nil {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		environment := config.Lookup("services", service.Name, "environment")
+		for _, name := range names {
+			warnings = append(warnings, Warning{
+				Kind:     WarningUnset,
+				Section:  "service",
+				Name:     service.Name,
+				Property: "environment." + name,
+				Message:  fmt.Sprintf("%s is not set in the environment, it will not be passed to the container", name),
+				Position: environmentEntryPosition(environment, name),
+			})
+		}
+	}
+	return warnings
+}
+
+// environmentEntryPosition returns the position of the variable name in an
+// environment mapping or list
+func environmentEntryPosition(environment *types.Node, name string) types.Position {
+	if item := environment.Item(name); item != nil {
+		return item.KeyPosition
+	}
+	if environment != nil {
+		for _, elem := range environment.Elements {
+			if value, ok := elem.Value.(string); ok && strings.SplitN(value, "=", 2)[0] == name {
+				return elem.Position
+			}
+		}
+		return environment.Position
+	}
+	return types.Position{}
+}
+
+// keyPosition returns the position of the key at path in config, where path
+// uses the same format as Warning.Path
+func keyPosition(config *types.Node, path string) types.Position {
+	current := config
+	var position types.Position
+	for _, segment := range pathSegmentRegexp.FindAllStringSubmatch(path, -1) {
+		if current == nil {
+			return types.Position{}
+		}
+		if strings.HasPrefix(segment[0], "[") {
+			index, err := strconv.Atoi(segment[1])
+			if err != nil || current.Kind != types.SequenceNode || index < 0 || index >= len(current.Elements) {
+				return types.Position{}
+			}
+			current = current.Elements[index]
+			position = current.Position
+			continue
+		}
+		item := current.Item(segment[0])
+		if item == nil {
+			return types.Position{}
+		}
+		current = item.Value
+		position = item.KeyPosition
+	}
+	return position
+}
+
+// toYAMLPath converts a key reported by mapstructure, which names struct
+// fields by their Go name, into the path of the key in the Compose file
+func toYAMLPath(target reflect.Type, key string) string {
+	var parts []string
+	current := target
+	for _, match := range pathSegmentRegexp.FindAllStringSubmatch(key, -1) {
+		segment := match[0]
+		current = types.IndirectType(current)
+
+		if strings.HasPrefix(segment, "[") {
+			index := match[1]
+			if current != nil && current.Kind() == reflect.Slice {
+				if len(parts) > 0 {
+					parts[len(parts)-1] += segment
+				} else {
+					parts = append(parts, segment)
+				}
+			} else {
+				parts = append(parts, index)
+			}
+			if current != nil && (current.Kind() == reflect.Map || current.Kind() == reflect.Slice) {
+				current = current.Elem()
+			} else {
+				current = nil
+			}
+			continue
+		}
+
+		if current != nil && current.Kind() == reflect.Struct {
+			if field, ok := current.FieldByName(segment); ok {
+				parts = append(parts, types.FieldName(field))
+				current = field.Type
+				continue
+			}
+		}
+		parts = append(parts, segment)
+		current = nil
+	}
+	return strings.Join(parts, ".")
+}
+
+func prefixKeys(prefix string, keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = prefix + "." + key
+	}
+	return prefixed
+}
+
+func withoutKeys(keys []string, exclude []string) []string {
+	var filtered []string
+	for _, key := range keys {
+		if !types.ContainsString(exclude, key) {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,496 @@
+// Package migrate converts Compose files of version 1 and 2.x to version 3.
+// The compose-migrate command in cmd/compose-migrate does the same from the
+// command line.
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aanand/compose-file/loader"
+	"github.com/aanand/compose-file/schema"
+	"github.com/aanand/compose-file/types"
+)
+
+const (
+	defaultCPUPeriod = 100000
+	defaultCPUShares = 1024
+)
+
+var (
+	versionRegexp    = regexp.MustCompile(`^2(\.\d+)?$`)
+	volumeNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+)
+
+var sectionKeys = map[string]string{
+	"service": "services",
+	"network": "networks",
+	"volume":  "volumes",
+}
+
+// Issue describes a property which could not be migrated, and was removed
+type Issue struct {
+	// Section is the kind of resource the property belongs to: service,
+	// network or volume
+	Section string
+	// Name is the name of the service, network or volume
+	Name     string
+	Property string
+	// Message explains how to replace the property
+	Message  string
+	Position types.Position
+}
+
+// Path returns the full path of the property, for example
+// services.web.extends
+func (i Issue) Path() string {
+	return strings.Join([]string{sectionKeys[i.Section], i.Name, i.Property}, ".")
+}
+
+func (i Issue) String() string {
+	if i.Position.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", i.Position, i.Path(), i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.Path(), i.Message)
+}
+
+// File converts the content of a version 1 or 2.x Compose file to version 3,
+// and returns the content of the new file along with the properties which
+// could not be migrated. filename is the name of the file, which is given in
+// the position of each issue, and may be empty.
+func File(filename string, source []byte) ([]byte, []Issue, error) {
+	config, err := loader.ParseYAML(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if filename != "" {
+		config.SetFilename(filename)
+	}
+	migrated, issues, err := Migrate(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := loader.MarshalYAML(migrated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, issues, nil
+}
+
+// Migrate converts a parsed version 1 or 2.x Compose file to version 3. The
+// source is not modified, and the order of keys and comments are kept.
+//
+// Resource limits move to deploy.resources: mem_limit and mem_reservation
+// become the memory limit and reservation, cpus and cpu_quota/cpu_period the
+// cpus limit, and cpu_shares the cpus reservation, counting 1024 shares as one
+// CPU. Since shares are relative weights, the reservation is an approximation.
+//
+// volume_driver sets the driver of the top-level volumes the service uses, and
+// its anonymous volumes are turned into named volumes so that the driver
+// applies to them. volumes_from copies the volumes of another service, turning
+// its anonymous volumes into named volumes which both services share. The
+// volumes_from of that service are resolved first, so that chains of services
+// get all the volumes.
+//
+// Any property which has no equivalent in version 3 is removed, and returned
+// as an Issue.
+func Migrate(source *types.Node) (*types.Node, []Issue, error) {
+	m := &migrator{}
+
+	var version string
+	if value := source.Lookup("version"); !value.IsNull() {
+		version = fmt.Sprint(value.Value)
+	}
+	switch {
+	case source.Item("version") == nil:
+		m.config = &types.Node{Kind: types.MappingNode}
+		m.config.Set("version", types.NewNode("3"))
+		m.config.Set("services", source.Copy())
+		m.v1 = true
+	case versionRegexp.MatchString(version):
+		m.config = source.Copy()
+		m.config.Item("version").Value.Value = "3"
+	default:
+		return nil, nil, fmt.Errorf("Unsupported Compose file version: %#v. Only version 1 and 2.x files can be migrated", version)
+	}
+
+	services := m.config.Lookup("services")
+	if services == nil {
+		services = &types.Node{Kind: types.MappingNode}
+		m.config.Set("services", services)
+	}
+	m.services = services
+
+	for _, item := range services.Items {
+		if item.Value.Kind != types.MappingNode {
+			continue
+		}
+		if m.v1 {
+			m.migrateV1(item.Value)
+		}
+		m.migrateResources(item.Key, item.Value)
+		m.migrateDependsOn(item.Key, item.Value)
+	}
+	for _, item := range services.Items {
+		m.migrateVolumesFrom(item.Key, item.Value)
+	}
+	for _, item := range services.Items {
+		m.migrateVolumeDriver(item.Key, item.Value)
+	}
+
+	if err := m.removeUnsupported("service", services); err != nil {
+		return nil, nil, err
+	}
+	if err := m.removeUnsupported("network", m.config.Lookup("networks")); err != nil {
+		return nil, nil, err
+	}
+	if err := m.removeUnsupported("volume", m.config.Lookup("volumes")); err != nil {
+		return nil, nil, err
+	}
+	return m.config, m.issues, nil
+}
+
+type migrator struct {
+	config   *types.Node
+	services *types.Node
+	v1       bool
+	issues   []Issue
+}
+
+func (m *migrator) report(section, name string, item *types.MapItem, message string) {
+	m.issues = append(m.issues, Issue{
+		Section:  section,
+		Name:     name,
+		Property: item.Key,
+		Message:  message,
+		Position: item.KeyPosition,
+	})
+}
+
+// forbidden reports a property using its message in types.ForbiddenProperties,
+// and removes it from the service
+func (m *migrator) forbidden(name string, service *types.Node, key string) {
+	if item := service.Item(key); item != nil {
+		m.report("service", name, item, types.ForbiddenProperties[key])
+		service.Delete(key)
+	}
+}
+
+// migrateV1 converts the service properties which were renamed in version 2
+func (m *migrator) migrateV1(service *types.Node) {
+	if item := service.Item("net"); item != nil {
+		service.Delete("net")
+		if service.Item("network_mode") == nil {
+			service.Set("network_mode", item.Value)
+		}
+	}
+
+	if item := service.Item("dockerfile"); item != nil {
+		service.Delete("dockerfile")
+		if build, ok := service.Get("build"); ok && build.Kind == types.ScalarNode {
+			context := &types.Node{Kind: types.MappingNode, Position: build.Position}
+			context.Set("context", build)
+			context.Set("dockerfile", item.Value)
+			service.Set("build", context)
+		}
+	}
+
+	driver := service.Item("log_driver")
+	options := service.Item("log_opt")
+	if driver != nil || options != nil {
+		logging := &types.Node{Kind: types.MappingNode}
+		if driver != nil {
+			logging.Set("driver", driver.Value)
+			service.Delete("log_driver")
+		}
+		if options != nil {
+			logging.Set("options", options.Value)
+			service.Delete("log_opt")
+		}
+		if service.Item("logging") == nil {
+			service.Set("logging", logging)
+		}
+	}
+}
+
+func (m *migrator) migrateResources(name string, service *types.Node) {
+	if value, ok := service.Get("mem_limit"); ok {
+		if memory, ok := memoryValue(value); ok {
+			setResource(service, "limits", "memory", memory)
+			service.Delete("mem_limit")
+		} else {
+			m.forbidden(name, service, "mem_limit")
+		}
+	}
+
+	if value, ok := service.Get("mem_reservation"); ok {
+		if memory, ok := memoryValue(value); ok {
+			setResource(service, "reservations", "memory", memory)
+			service.Delete("mem_reservation")
+		}
+	}
+
+	if value, ok := service.Get("cpus"); ok {
+		if cpus, ok := numberValue(value); ok {
+			setResource(service, "limits", "cpus", cpusNode(value, cpus))
+			service.Delete("cpus")
+			service.Delete("cpu_quota")
+			service.Delete("cpu_period")
+		}
+	}
+
+	if value, ok := service.Get("cpu_quota"); ok {
+		quota, quotaOK := numberValue(value)
+		period, periodOK := float64(defaultCPUPeriod), true
+		if periodValue, ok := service.Get("cpu_period"); ok {
+			period, periodOK = numberValue(periodValue)
+		}
+		if quotaOK && periodOK && period > 0 {
+			setResource(service, "limits", "cpus", cpusNode(value, quota/period))
+			service.Delete("cpu_quota")
+			service.Delete("cpu_period")
+		} else {
+			m.forbidden(name, service, "cpu_quota")
+		}
+	}
+
+	if value, ok := service.Get("cpu_shares"); ok {
+		if shares, ok := numberValue(value); ok {
+			setResource(service, "reservations", "cpus", cpusNode(value, shares/defaultCPUShares))
+			service.Delete("cpu_shares")
+		} else {
+			m.forbidden(name, service, "cpu_shares")
+		}
+	}
+}
+
+// setResource sets deploy.resources.<kind>.<key>, creating the mappings on
+// the way, unless it is already set
+func setResource(service *types.Node, kind string, key string, value *types.Node) {
+	resources := mapping(mapping(mapping(service, "deploy"), "resources"), kind)
+	if _, ok := resources.Get(key); !ok {
+		resources.Set(key, value)
+	}
+}
+
+// mapping returns the mapping under key, adding an empty one if it is missing
+func mapping(parent *types.Node, key string) *types.Node {
+	if value, ok := parent.Get(key); ok && value.Kind == types.MappingNode {
+		return value
+	}
+	value := &types.Node{Kind: types.MappingNode}
+	parent.Set(key, value)
+	return value
+}
+
+// memoryValue returns a memory size as the string version 3 expects
+func memoryValue(value *types.Node) (*types.Node, bool) {
+	out := value.Copy()
+	switch v := value.Value.(type) {
+	case string:
+		return out, true
+	case int:
+		out.Value = strconv.Itoa(v)
+		return out, true
+	}
+	return nil, false
+}
+
+func numberValue(value *types.Node) (float64, bool) {
+	switch v := value.Value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func cpusNode(value *types.Node, cpus float64) *types.Node {
+	out := value.Copy()
+	out.Value = strconv.FormatFloat(cpus, 'f', -1, 64)
+	return out
+}
+
+// migrateDependsOn converts the conditions of version 2.1 to a list of
+// services, which only keeps the start order
+func (m *migrator) migrateDependsOn(name string, service *types.Node) {
+	item := service.Item("depends_on")
+	if item == nil || item.Value.Kind != types.MappingNode {
+		return
+	}
+	list := &types.Node{Kind: types.SequenceNode, Position: item.Value.Position}
+	for _, dependency := range item.Value.Items {
+		list.Elements = append(list.Elements, &types.Node{
+			Kind:     types.ScalarNode,
+			Value:    dependency.Key,
+			Position: dependency.KeyPosition,
+		})
+	}
+	m.report("service", name, item, "Conditions are not supported, services are only started in order.")
+	item.Value = list
+}
+
+func (m *migrator) migrateVolumesFrom(name string, service *types.Node) {
+	item := service.Item("volumes_from")
+	if item == nil {
+		return
+	}
+	service.Delete("volumes_from")
+	if item.Value.Kind != types.SequenceNode {
+		m.report("service", name, item, types.ForbiddenProperties["volumes_from"])
+		return
+	}
+
+	for _, elem := range item.Value.Elements {
+		entry, _ := elem.Value.(string)
+		parts := strings.Split(entry, ":")
+		source, ok := m.services.Get(parts[0])
+		if parts[0] == "container" || !ok || source.Kind != types.MappingNode || len(parts) > 2 {
+			m.report("service", name, item, types.ForbiddenProperties["volumes_from"])
+			continue
+		}
+		// The service may itself use volumes_from, which is removed once it
+		// is resolved, so that a cycle ends here
+		m.migrateVolumesFrom(parts[0], source)
+
+		var mode string
+		if len(parts) == 2 {
+			mode = parts[1]
+		}
+
+		sourceVolumes, _ := source.Get("volumes")
+		if sourceVolumes == nil {
+			continue
+		}
+		for _, volume := range sourceVolumes.Elements {
+			if _, ok := volume.Value.(string); !ok {
+				m.report("service", name, item, types.ForbiddenProperties["volumes_from"])
+				continue
+			}
+			parsed, err := types.ParseVolumeSpec(m.nameAnonymousVolume(parts[0], volume))
+			if err != nil {
+				m.report("service", name, item, err.Error())
+				continue
+			}
+			if mode != "" {
+				parsed.Mode = mode
+			}
+			m.addVolume(service, parsed, elem.Position)
+		}
+	}
+}
+
+// nameAnonymousVolume turns an anonymous volume of a service into a named
+// volume, declares it at the top level, and returns the new volume spec.
+// Anonymous volumes at a Windows path are left as they are.
+func (m *migrator) nameAnonymousVolume(service string, volume *types.Node) string {
+	spec := volume.Value.(string)
+	parsed, err := types.ParseVolumeSpec(spec)
+	if err != nil || parsed.Source != "" || types.HasDriveLetter(parsed.Target) {
+		return spec
+	}
+	name := service + "_" + strings.Trim(volumeNameRegexp.ReplaceAllString(spec, "_"), "_")
+	m.declareVolume(name)
+	spec = name + ":" + spec
+	volume.Value = spec
+	return spec
+}
+
+func (m *migrator) declareVolume(name string) *types.Node {
+	volumes := mapping(m.config, "volumes")
+	volume, ok := volumes.Get(name)
+	if !ok || volume.IsNull() {
+		volume = &types.Node{Kind: types.MappingNode}
+		volumes.Set(name, volume)
+	}
+	return volume
+}
+
+// addVolume adds a volume to a service, unless it already has a volume with
+// the same target
+func (m *migrator) addVolume(service *types.Node, volume types.VolumeSpec, position types.Position) {
+	volumes, ok := service.Get("volumes")
+	if !ok || volumes.Kind != types.SequenceNode {
+		volumes = &types.Node{Kind: types.SequenceNode}
+		service.Set("volumes", volumes)
+	}
+	for _, existing := range volumes.Elements {
+		if existing, ok := existing.Value.(string); ok {
+			if parsed, err := types.ParseVolumeSpec(existing); err == nil && parsed.Target == volume.Target {
+				return
+			}
+		}
+	}
+	volumes.Elements = append(volumes.Elements, &types.Node{
+		Kind:     types.ScalarNode,
+		Value:    volume.String(),
+		Position: position,
+	})
+}
+
+func (m *migrator) migrateVolumeDriver(name string, service *types.Node) {
+	item := service.Item("volume_driver")
+	if item == nil {
+		return
+	}
+	service.Delete("volume_driver")
+	driver := item.Value
+
+	volumes, _ := service.Get("volumes")
+	if volumes == nil {
+		return
+	}
+	for _, volume := range volumes.Elements {
+		if _, ok := volume.Value.(string); !ok {
+			continue
+		}
+		parsed, err := types.ParseVolumeSpec(m.nameAnonymousVolume(name, volume))
+		if err != nil || !parsed.IsNamedVolume() {
+			continue
+		}
+
+		declared := m.declareVolume(parsed.Source)
+		existing, ok := declared.Get("driver")
+		switch {
+		case !ok:
+			declared.Set("driver", driver.Copy())
+		case existing.Value != driver.Value:
+			m.report("service", name, item, types.ForbiddenProperties["volume_driver"])
+		}
+	}
+}
+
+// removeUnsupported removes the properties of the resources in section which
+// are not part of version 3
+func (m *migrator) removeUnsupported(section string, resources *types.Node) error {
+	if resources == nil {
+		return nil
+	}
+	allowed, err := schema.Properties(section)
+	if err != nil {
+		return err
+	}
+	for _, resource := range resources.Items {
+		if resource.Value == nil || resource.Value.Kind != types.MappingNode {
+			continue
+		}
+		for _, key := range resource.Value.Keys() {
+			if types.ContainsString(allowed, key) {
+				continue
+			}
+			message, ok := types.ForbiddenProperties[key]
+			if !ok {
+				message = fmt.Sprintf("`%s` is not supported in version 3.", key)
+			}
+			m.report(section, resource.Key, resource.Value.Item(key), message)
+			resource.Value.Delete(key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/aanand/compose-file/loader"
+	"github.com/aanand/compose-file/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func migrateString(t *testing.T, source string) (string, []Issue) {
+	out, issues, err := File("", []byte(source))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return string(out), issues
+}
+
+func issuePaths(issues []Issue) []string {
+	var paths []string
+	for _, issue := range issues {
+		paths = append(paths, issue.Path())
+	}
+	return paths
+}
+
+func TestMigrateResources(t *testing.T) {
+	out, issues := migrateString(t, `
+version: "2.1"
+services:
+  web:
+    image: nginx
+    mem_limit: 512m
+    mem_reservation: 268435456
+    cpu_shares: 512
+    cpu_quota: 50000
+`)
+	assert.Empty(t, issues)
+	assert.Equal(t, `version: "3"
+services:
+  web:
+    image: nginx
+    deploy:
+      resources:
+        limits:
+          memory: 512m
+          cpus: "0.5"
+        reservations:
+          memory: "268435456"
+          cpus: "0.5"
+`, out)
+}
+
+func TestMigrateVolumeDriver(t *testing.T) {
+	out, issues := migrateString(t, `
+version: "2"
+services:
+  db:
+    image: postgres
+    volume_driver: flocker
+    volumes:
+      - data:/var/lib/postgresql/data
+      - /backup
+      - ./config:/etc/postgresql
+      - C:\backups:C:\backups
+      - D:\scratch
+      - n:/srv
+volumes:
+  data: {}
+`)
+	assert.Empty(t, issues)
+	assert.Equal(t, `version: "3"
+services:
+  db:
+    image: postgres
+    volumes:
+      - data:/var/lib/postgresql/data
+      - db_backup:/backup
+      - ./config:/etc/postgresql
+      - C:\backups:C:\backups
+      - D:\scratch
+      - n:/srv
+volumes:
+  data:
+    driver: flocker
+  db_backup:
+    driver: flocker
+  n:
+    driver: flocker
+`, out)
+}
+
+func TestMigrateVolumesFrom(t *testing.T) {
+	out, issues := migrateString(t, `
+version: "2"
+services:
+  app:
+    image: app
+    volumes_from:
+      - data:ro
+      - container:legacy
+  data:
+    image: busybox
+    volumes:
+      - /srv/data
+      - logs:/var/log
+volumes:
+  logs:
+`)
+	assert.Equal(t, []string{"services.app.volumes_from"}, issuePaths(issues))
+	assert.Equal(t, types.ForbiddenProperties["volumes_from"], issues[0].Message)
+	assert.Equal(t, 6, issues[0].Position.Line)
+
+	assert.Equal(t, `version: "3"
+services:
+  app:
+    image: app
+    volumes:
+      - data_srv_data:/srv/data:ro
+      - logs:/var/log:ro
+  data:
+    image: busybox
+    volumes:
+      - data_srv_data:/srv/data
+      - logs:/var/log
+volumes:
+  logs: null
+  data_srv_data: {}
+`, out)
+}
+
+func TestMigrateVolumesFromChain(t *testing.T) {
+	out, issues := migrateString(t, `
+version: "2"
+services:
+  app:
+    image: app
+    volumes_from:
+      - backup
+  backup:
+    image: backup
+    volumes_from:
+      - data:ro
+    volumes:
+      - /backup
+  data:
+    image: busybox
+    volumes:
+      - /srv/data
+`)
+	assert.Empty(t, issues)
+	assert.Equal(t, `version: "3"
+services:
+  app:
+    image: app
+    volumes:
+      - backup_backup:/backup
+      - data_srv_data:/srv/data:ro
+  backup:
+    image: backup
+    volumes:
+      - backup_backup:/backup
+      - data_srv_data:/srv/data:ro
+  data:
+    image: busybox
+    volumes:
+      - data_srv_data:/srv/data
+volumes:
+  data_srv_data: {}
+  backup_backup: {}
+`, out)
+}
+
+func TestMigrateVolumesFromCycle(t *testing.T) {
+	out, issues := migrateString(t, `
+version: "2"
+services:
+  a:
+    image: a
+    volumes_from:
+      - b
+    volumes:
+      - /a
+  b:
+    image: b
+    volumes_from:
+      - a
+`)
+	assert.Empty(t, issues)
+	assert.Equal(t, `version: "3"
+services:
+  a:
+    image: a
+    volumes:
+      - a_a:/a
+  b:
+    image: b
+    volumes:
+      - a_a:/a
+volumes:
+  a_a: {}
+`, out)
+}
+
+func TestMigrateFileWithFilename(t *testing.T) {
+	_, issues, err := File("docker-compose.yml", []byte("version: \"2\"\nservices:\n  web:\n    extends: base\n"))
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, "docker-compose.yml:4:5: services.web.extends: "+types.ForbiddenProperties["extends"], issues[0].String())
+	}
+}
+
+func TestMigrateVolumesFromWhichIsNotAList(t *testing.T) {
+	out, issues := migrateString(t, `
+version: "2"
+services:
+  app:
+    image: app
+    volumes_from: data
+  data:
+    image: busybox
+`)
+	assert.Equal(t, []string{"services.app.volumes_from"}, issuePaths(issues))
+	assert.Equal(t, types.ForbiddenProperties["volumes_from"], issues[0].Message)
+	assert.Equal(t, 6, issues[0].Position.Line)
+
+	assert.Equal(t, `version: "3"
+services:
+  app:
+    image: app
+  data:
+    image: busybox
+`, out)
+}
+
+func TestMigrateUnsupportedProperties(t *testing.T) {
+	_, issues := migrateString(t, `
+version: "2.2"
+services:
+  web:
+    extends:
+      service: base
+    image: nginx
+    cpuset: "0,1"
+    oom_score_adj: 500
+    depends_on:
+      db:
+        condition: service_healthy
+  db:
+    image: postgres
+volumes:
+  data:
+    labels:
+      backup: daily
+`)
+	assert.Equal(t, []string{
+		"services.web.depends_on",
+		"services.web.extends",
+		"services.web.cpuset",
+		"services.web.oom_score_adj",
+		"volumes.data.labels",
+	}, issuePaths(issues))
+	assert.Equal(t, types.ForbiddenProperties["extends"], issues[1].Message)
+	assert.Equal(t, types.ForbiddenProperties["cpuset"], issues[2].Message)
+	assert.Equal(t, "`oom_score_adj` is not supported in version 3.", issues[3].Message)
+}
+
+func TestMigrateVersion1(t *testing.T) {
+	out, issues := migrateString(t, `
+web:
+  build: .
+  dockerfile: Dockerfile.dev
+  net: host
+  log_driver: syslog
+  log_opt:
+    tag: web
+`)
+	assert.Empty(t, issues)
+	assert.Equal(t, `version: "3"
+services:
+  web:
+    build:
+      context: .
+      dockerfile: Dockerfile.dev
+    network_mode: host
+    logging:
+      driver: syslog
+      options:
+        tag: web
+`, out)
+}
+
+func TestMigrateKeepsComments(t *testing.T) {
+	out, _ := migrateString(t, `# production stack
+version: "2"
+services:
+  web:
+    # pinned for now
+    image: nginx:1.10 # see upgrade notes
+`)
+	assert.Equal(t, `# production stack
+version: "3"
+services:
+  web:
+    # pinned for now
+    image: nginx:1.10 # see upgrade notes
+`, out)
+}
+
+func TestMigrateResultLoads(t *testing.T) {
+	source, err := loader.ParseYAML([]byte(`
+version: "2"
+services:
+  web:
+    image: nginx
+    mem_limit: 1g
+    volume_driver: local
+    volumes:
+      - /cache
+`))
+	assert.NoError(t, err)
+
+	migrated, issues, err := Migrate(source)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	assert.Equal(t, "2", source.Lookup("version").Value)
+
+	config, err := loader.Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{{Filename: "filename.yml", Config: migrated}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.UnitBytes(1<<30), config.Services[0].Deploy.Resources.Limits.MemoryBytes)
+	assert.Equal(t, "local", config.Volumes["web_cache"].Driver)
+}
+
+func TestMigrateUnsupportedVersion(t *testing.T) {
+	_, _, err := File("", []byte("version: \"3\"\nservices: {}\n"))
+	assert.EqualError(t, err, `Unsupported Compose file version: "3". Only version 1 and 2.x files can be migrated`)
+}
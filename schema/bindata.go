@@ -1,6 +1,7 @@
 // Code generated by go-bindata.
 // sources:
 // data/config_schema_v3.0.json
+// data/config_schema_v3.8.json
 // DO NOT EDIT!
 
 package schema
@@ -88,6 +89,26 @@ func dataConfig_schema_v30Json() (*asset, error) {
 	return a, nil
 }
 
+var _dataConfig_schema_v38Json = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xed\x5b\x4b\x8f\xdb\x36\x10\xbe\xfb\x57\x2c\x94\xdc\xea\xdd\x0d\xd0\xa0\x68\x73\xeb\xb1\xa7\xf6\x5c\x43\x11\x68\x89\x96\x99\x15\x1f\x21\x29\xef\x3a\x81\xff\x7b\x49\xbd\x2c\x51\xa4\x48\xc9\x5a\x6c\x10\x34\xa7\x8d\x34\x33\x24\x87\x1f\xbf\x79\x50\xfe\xbe\xb9\xbb\x8b\xde\x8b\xf4\x08\x31\x88\x3e\xdd\x45\x47\x29\xd9\xa7\xc7\xc7\x2f\x82\x92\xfb\xfa\xe9\x03\xe5\xf9\x63\xc6\xc1\x41\xde\x7f\xf8\xf8\x58\x3f\x7b\x17\x6d\xb5\x1e\xca\xb4\x4a\x4a\xc9\x01\xe5\x49\xfd\x26\x39\xfd\xfa\xf0\xfb\x83\x56\xaf\x45\xe4\x99\x41\x2d\x44\xf7\x5f\x60\x2a\xeb\x67\x1c\x7e\x2d\x11\x87\x5a\x79\x17\x9d\x20\x17\x48\x49\xc7\xdb\x8d\x7e\xc7\x38\x65\x90\x4b\x04\x85\x7a\xfb\x5d\x3d\x51\xcf\x5a\x91\xf6\x41\xcf\xac\x90\x1c\x91\x3c\xaa\x1e\x5f\x2a\x0b\xea\xa5\x80\xfc\x84\xd2\x9e\x85\x6e\xaa\xef\x1e\xaf\xf6\x1f\x3b\xb1\xad\x69\xb5\x37\xd9\xea\x39\x03\x52\x42\x4e\xfe\x19\xcf\xad\x7a\xfd\x79\x07\xee\xbf\xfd\x79\xff\xef\x87\xfb\x3f\x1e\x92\xfb\xf8\x97\xf7\x83\xd7\xda\xbf\x1c\x1e\xea\xe1\x33\x78\x40\x04\x49\xb5\x9a\x6e\xfc\xa8\x93\xbc\x34\x7f\x5d\xba\x81\x41\x96\x55\xc2\xa0\x18\x8c\x7d\x00\x85\x80\xc3\x35\x13\x28\x9f\x29\x7f\xf2\xad\xb9\x13\x7b\xa3\x35\x37\xe3\x5b\xd6\x3c\x5c\xce\x89\x16\x25\xf6\xee\x60\x2b\xf5\x46\x8b\xa9\x87\xbf\x6d\xff\x36\xed\xa2\x27\x65\x6b\x89\xde\xd8\xd5\x04\x07\x68\xb7\xb9\xca\x86\x36\xb7\xaf\x3a\x67\x39\xbc\x94\x41\x56\xd0\xb3\x7e\xe6\xf0\x47\x2d\x80\x21\x91\x51\xe7\x02\xa5\xb7\x2f\x51\x91\x99\x1e\xa5\x04\xfe\xad\x4d\xec\x7a\x0f\xef\x94\x65\xe3\x60\xf7\xec\x54\xef\x07\xff\x73\x6f\x78\xf7\xde\xb1\x96\xee\xbd\xe2\x2e\x09\x5f\x64\xb5\xa8\xe9\xa1\x6b\x17\xd0\xf4\x09\xf2\x03\x2a\x60\xa8\x06\xe0\xb9\x98\x70\x59\x81\x84\x4c\x28\x4f\x32\xa4\x66\x7f\x31\xd4\x47\xf6\xfc\x78\x32\xa1\xa8\xff\xc5\x1b\x8b\xc1\x28\x05\x2c\x51\xe6\x06\xeb\x00\x9c\x83\x73\xb4\x55\x00\x92\x10\x0b\xfb\x12\xef\xa2\x92\xa0\xaf\x25\xfc\xab\x11\x91\xbc\x84\xa6\xdd\x4c\x4d\x6e\x7d\xc3\x39\xa7\x25\x4b\x18\xe0\x1a\x60\xd3\xee\x57\xfb\x8a\x31\x20\x6b\xa1\x6e\xce\x3a\x02\x3c\xaf\x30\x07\x10\x81\x3c\x21\x00\xfb\x80\xa4\x4f\x1d\x24\x99\x48\xea\xf8\x37\x09\xa3\x43\x52\xeb\x0b\xc3\x40\x17\x0c\x57\xdd\x8f\x8c\x4c\x01\xbb\x36\xa3\xa1\xad\xe7\x16\x19\x8a\x89\x80\x80\xa7\xc7\x85\xfa\x14\x2b\xf7\x85\xf8\x4e\x01\x85\x9f\x19\x45\x35\x5e\x7e\x38\x20\x40\x72\x4a\x5a\x2e\x79\x45\x6e\x6c\xe6\x6a\xbe\xee\x66\x3e\x26\x2d\xfb\x04\xc2\xa6\x61\x9f\x4c\x18\x5d\x87\x91\xf6\x35\xa6\x1f\x03\x59\xb8\xd1\xe8\xe5\x9d\x57\xad\x3d\xa5\x05\x04\x64\x44\xbe\x0e\x0a\x1e\x9b\xda\xd5\x33\x89\x1d\x92\xc1\x94\x3d\x26\x6e\x13\x3b\x36\x89\x20\x94\x21\x4e\x09\x6e\x59\x33\x24\x10\x75\xc9\x80\xd6\x7f\x61\x54\x8c\x30\xea\x06\x97\x03\x58\xad\xc6\xae\xdd\x27\x75\x78\x48\x89\xf7\x90\x9b\xae\x8b\x0e\x94\x63\xa0\x27\xdb\x8e\xbd\x71\x6c\x88\x85\xa1\xfa\x2e\xe8\xaf\x41\xa7\x7f\xa0\x50\x64\x42\x9e\xd6\xa7\x42\x65\x9e\x83\xe4\x48\x85\x14\x33\x5c\xdc\xa9\x1f\x21\x28\xe4\x51\x95\x4f\xe9\xd3\x84\x7a\x5f\x6a\xa0\xad\x86\x0d\x21\x43\x84\x41\xee\x17\x62\xa9\x4f\xa4\x00\x7b\x58\x2c\x5a\xe7\xaa\xce\xef\x99\xa5\x79\xae\x45\x5d\x88\x1b\x65\xb8\x81\xb9\x61\xc6\x91\xaa\x3c\x43\x13\x3d\xca\xae\x89\xf9\x32\xe6\xf3\x54\x29\x03\xd1\xcf\x0f\x75\x91\x32\x71\xaa\xaa\xbf\x8a\x22\x8a\x2f\x41\x34\x63\xd0\x8a\x71\x24\x83\x58\x6c\xb0\x2b\x18\xa4\x3a\xbf\xe4\x50\x08\x1f\xa2\x9a\xa2\x30\xc1\x34\x73\x01\x74\x24\x2c\x42\x83\xe6\xec\x84\x69\x59\x9d\x11\xb4\x75\xde\x42\x33\x28\x02\xdf\xb9\x63\x62\x50\x7c\x0d\x8d\xb0\x7a\xdb\x0b\x04\x04\x14\xcb\x32\xcf\x91\x35\xc4\x4e\x1f\x03\x31\x61\xd3\xfd\x6d\xa9\xae\xe6\x9d\xa4\xa0\xa9\xe2\x7f\xc4\xd6\x5a\x4c\x38\xbe\x2d\xde\x47\x94\x23\x79\x1e\xe8\xa9\x24\x15\xe6\xea\xd8\x5e\x1c\x7a\xee\xb4\x66\x66\x86\xe1\x34\x75\x9d\x4b\xc5\x1b\xb6\x89\xc4\x1b\x1f\x91\xbc\x6a\xcd\xca\x50\xe6\x26\xbd\x8a\xea\xfa\x4c\xc1\x28\x97\xe2\x6d\xf2\x96\x7a\xe8\x9b\xd3\x16\x05\x94\x93\xaa\x0f\x72\x57\xca\xda\x73\x0d\x87\x20\x53\x35\x62\x71\x0e\x90\x14\x12\x70\x6f\x05\x2d\x60\x5a\x6a\x90\x26\x2a\xb0\xad\x9e\x30\x89\x23\x4e\x04\xfa\x06\x87\xbb\x79\x0d\x5c\x8d\xa1\x78\xa0\x73\x16\xa9\x5c\x96\x78\x08\x99\x21\xa2\x16\x02\x89\xd7\x3b\x42\x52\xa6\xa6\x96\x2b\xb8\x7a\x3d\xa4\x45\x73\x0e\x52\x98\x28\x58\x23\x9a\xd9\x14\xb6\x7d\x58\x64\x25\x07\x7a\xaa\x03\x33\x12\xb3\xc3\xc2\x4a\x5a\x4a\xff\x76\x97\x05\xc2\xc8\x7d\x0e\x2c\x91\x22\x20\x98\xd5\x81\xcc\x1e\xbf\x26\x62\x97\x85\xed\xc6\x54\x34\x91\x3e\x4d\x67\x4f\x01\x69\xd3\x11\xf0\xcc\xce\xba\x8e\xea\x4d\xd0\x83\x0c\xa6\x69\xab\x91\x61\xa9\x58\xd9\xdb\x36\x13\xb1\x96\x8c\xf3\xe8\xdc\x9c\x46\xec\x64\xd4\x8b\x95\x51\x4b\xe1\xcd\x70\x2b\x19\x22\xa6\xb2\xb3\x4e\x74\xdc\xb6\x5f\x48\xba\x2e\x0c\xf9\xe3\xac\x25\xeb\xf2\x66\x45\x7e\xec\xb4\x36\x6c\xe7\x1b\x2a\xde\xaa\x36\xb7\x3e\xc9\xb1\x1d\x06\x8a\x73\x73\x28\x83\x53\x85\x2b\x2d\xbc\x7e\x27\x65\xcc\xc4\x2d\xca\xed\x54\x6c\x66\x42\x06\x30\xec\xea\xb3\xda\x2b\x37\x37\x4d\x6c\x15\xda\xe0\x20\x56\x73\xdd\x76\xdb\x12\xdb\x5a\xf7\xe1\x93\x18\x4e\x20\x5e\x18\xf8\x3b\x31\x5d\xe0\x68\xce\xcf\x90\xfd\x70\xde\x70\x4f\x68\xf4\x1a\xa6\x6e\x8c\xfa\xa2\xe6\xad\xd1\xae\x03\x5e\x9b\x7a\x6d\x7d\xd7\x47\x1a\x14\xfc\x34\x08\xab\x36\x0a\x91\x08\x43\x5a\x4a\x8f\x14\x87\xea\x99\xd1\xd4\x6e\xd2\x87\x81\x31\x95\xeb\xfc\x90\xad\xdf\x0c\x09\xb0\x37\x6e\x91\xcc\x4e\xe4\xbc\xed\xed\x5d\xd1\xb5\xad\xbe\xa9\xcd\xed\x49\xae\xb0\xb7\x21\xb1\x81\xab\x11\x51\x0a\x84\x2f\xfe\xde\xd0\x60\x2a\x59\x06\x24\x4c\xea\x0f\x12\x66\x65\x3c\x13\xa9\x0e\x03\x1c\x14\x05\x54\x83\xe2\x90\xd4\x41\xed\x41\x01\xce\x8b\x52\xc1\xba\x88\x00\xa8\x28\x39\x4c\x40\x2a\x9b\x6f\x1e\x3c\xc8\x54\xce\x57\x8e\xa1\x7c\xf9\x90\x18\xbc\x24\xed\xb0\x95\x88\xe7\x58\xd5\xc7\x88\x67\x70\x92\x9d\xc6\xec\x17\xda\x44\xba\x42\x86\x16\xc5\x1e\xa4\x4f\xff\xef\xe8\x4f\xb3\xa3\x50\xd0\x92\xa7\x50\xac\xb5\x97\xd7\xea\xc6\x41\x16\xed\x88\xa3\xe5\xaa\x17\x3a\x1e\x75\x5d\x5b\xaf\xbe\x37\x99\x6e\x2a\xeb\x84\x51\x45\x74\xe7\xb5\x56\xa8\xb0\x5f\x3b\x39\x04\x39\x37\x42\x55\xe3\x46\x17\x7f\x98\x49\x11\x74\x34\x9e\x11\xc9\xe8\xf3\x8c\x01\xd7\x83\x12\x2b\x54\xe5\x6d\x84\xba\x5b\x1d\xad\xe6\x0e\xd4\x52\x67\x5f\x52\x98\x6e\x61\x0a\x4a\x90\x43\x92\x5a\x13\xef\x1b\x6e\x66\xd7\x28\x65\x04\xd3\xcd\x22\xdf\x41\x0f\xcc\xa3\x1b\x63\xab\xe6\xcf\x36\x54\xb6\xd9\x83\x6e\xb5\x24\xc4\x4c\x37\x7a\x05\x07\x56\x27\x17\x57\x05\xd9\x87\x5b\xc1\x76\x43\x9a\xdd\xb1\x86\x27\x0d\xeb\xe4\xfc\x9f\xb0\x39\x52\xaf\x94\x95\xde\x0b\x16\x0c\x31\xe5\xe7\xb5\x4b\x89\xf6\x5b\x3e\xcf\x12\x5b\xb1\x15\xd2\xcc\xa0\xef\x65\x42\x6e\xed\x1a\x29\xdd\xdb\x5c\xbd\x33\xe6\xbf\x99\x8b\xfd\xa1\x04\x31\x80\xd7\xe2\xb5\xe0\x7b\xcc\xc8\x9a\x66\xfd\x08\x8c\x55\xee\xc9\x9c\xde\x49\xae\xaa\x80\x67\x7b\x1c\xb4\x2b\x20\xa6\xd2\x24\x92\xc3\x70\x0d\x50\xbe\xb4\xd7\x3f\xf0\xf6\x36\x8d\x0d\x56\x23\x24\xb9\xa2\xcd\xeb\x76\x4d\x56\x63\x71\xf7\x05\xf9\xb2\xdb\xd5\x19\xfe\x59\x76\x07\x75\x63\x7e\xd2\x7e\x73\xe2\x38\xc6\xbb\xae\xde\xdf\x76\x0b\x8f\x83\xcf\xb4\x93\x09\xd7\x9b\xbf\xf2\x39\x48\x8f\xee\x2e\xc5\xd6\x68\xed\x98\x37\x26\x36\x41\x48\xb4\xbd\x44\x5f\xe6\x7a\x65\x67\xb6\x01\x6e\x08\x64\xcd\x77\xdc\x9e\x38\xd6\x48\xad\x10\xc6\x7e\x8e\x10\xf5\xf6\xf8\x9e\xc8\xd4\x16\xf6\x90\x66\x80\xc6\xb8\x96\xeb\x81\x67\xdc\x60\x9c\xda\xe7\xe0\x6f\x11\x36\xfd\x7e\x62\x37\x0d\x53\xcc\xf2\x1b\x99\x61\xcc\x9e\xba\xb6\xdd\x4c\xb7\xa9\x8d\x41\x1b\xe7\x4d\xaf\x7c\x45\xd8\x3e\xfc\x32\x11\x3b\xa6\x3e\x80\x7a\x25\x86\x5f\xe1\x4a\xdc\xbe\xa7\x46\x21\xba\xe9\x6e\xe4\xcd\x1f\x7a\x38\x98\xaa\xa7\x3f\xfa\xd9\x87\x5e\x27\x39\x8f\x1a\xe0\xdf\x87\x55\x5d\xfd\x93\x8d\xe1\x9d\x8f\x21\x52\x7f\x4e\xd8\xe3\x89\xb8\x5f\x9b\xbb\xb6\xd1\xfa\x63\x10\xb3\xa6\x6c\x7f\x94\x11\x4f\x1f\xf6\xeb\x0f\x68\x36\x97\xcd\x7f\xd9\xb7\x8c\x88\xc8\x36\x00\x00")
+
+func dataConfig_schema_v38JsonBytes() ([]byte, error) {
+	return bindataRead(
+		_dataConfig_schema_v38Json,
+		"data/config_schema_v3.8.json",
+	)
+}
+
+func dataConfig_schema_v38Json() (*asset, error) {
+	bytes, err := dataConfig_schema_v38JsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/config_schema_v3.8.json", size: 14024, mode: os.FileMode(420), modTime: time.Unix(1792330616, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -141,6 +162,7 @@ func AssetNames() []string {
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
 	"data/config_schema_v3.0.json": dataConfig_schema_v30Json,
+	"data/config_schema_v3.8.json": dataConfig_schema_v38Json,
 }
 
 // AssetDir returns the file names below a certain
@@ -185,6 +207,7 @@ type bintree struct {
 var _bintree = &bintree{nil, map[string]*bintree{
 	"data": &bintree{nil, map[string]*bintree{
 		"config_schema_v3.0.json": &bintree{dataConfig_schema_v30Json, map[string]*bintree{}},
+		"config_schema_v3.8.json": &bintree{dataConfig_schema_v38Json, map[string]*bintree{}},
 	}},
 }}
 
@@ -3,8 +3,11 @@ package schema
 //go:generate go-bindata -pkg schema data
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/xeipuuv/gojsonschema"
@@ -30,17 +33,58 @@ func init() {
 	gojsonschema.FormatCheckers.Add("duration", durationFormatChecker{})
 }
 
-// Validate uses the jsonschema to validate the configuration
+// versions maps the versions of the Compose file format to the schema they are
+// validated against. Any other version is validated against the 3.0 schema,
+// and rejected by the loader.
+var versions = map[string]string{
+	"3":   "data/config_schema_v3.0.json",
+	"3.0": "data/config_schema_v3.0.json",
+	"3.8": "data/config_schema_v3.8.json",
+}
+
+type compiledSchema struct {
+	once   sync.Once
+	schema *gojsonschema.Schema
+	err    error
+}
+
+var compiledSchemas = map[string]*compiledSchema{
+	"data/config_schema_v3.0.json": {},
+	"data/config_schema_v3.8.json": {},
+}
+
+// compile parses the schema of version the first time it is called, and
+// returns the same result every time after
+func compile(version string) (*gojsonschema.Schema, error) {
+	name, ok := versions[version]
+	if !ok {
+		name = versions["3.0"]
+	}
+	compiled := compiledSchemas[name]
+	compiled.once.Do(func() {
+		schemaData, err := Asset(name)
+		if err != nil {
+			compiled.err = err
+			return
+		}
+		schemaLoader := gojsonschema.NewStringLoader(string(schemaData))
+		compiled.schema, compiled.err = gojsonschema.NewSchema(schemaLoader)
+	})
+	return compiled.schema, compiled.err
+}
+
+// Validate uses the jsonschema to validate the configuration, with the schema
+// of the version it declares
 func Validate(config map[string]interface{}) error {
-	schemaData, err := Asset("data/config_schema_v3.0.json")
+	version, _ := config["version"].(string)
+	compiled, err := compile(version)
 	if err != nil {
 		return err
 	}
 
-	schemaLoader := gojsonschema.NewStringLoader(string(schemaData))
 	dataLoader := gojsonschema.NewGoLoader(config)
 
-	result, err := gojsonschema.Validate(schemaLoader, dataLoader)
+	result, err := compiled.Validate(dataLoader)
 	if err != nil {
 		return err
 	}
@@ -111,3 +155,32 @@ func getMostSpecificError(errors []gojsonschema.ResultError) gojsonschema.Result
 func specificity(err gojsonschema.ResultError) int {
 	return len(strings.Split(err.Field(), "."))
 }
+
+// Properties returns the sorted names of the properties allowed by a
+// definition of the version 3.0 schema, such as service, network or volume
+func Properties(definition string) ([]string, error) {
+	schemaData, err := Asset(versions["3.0"])
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Definitions map[string]struct {
+			Properties map[string]json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(schemaData, &parsed); err != nil {
+		return nil, err
+	}
+
+	def, ok := parsed.Definitions[definition]
+	if !ok {
+		return nil, fmt.Errorf("Unknown schema definition: %s", definition)
+	}
+	var names []string
+	for name := range def.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -33,3 +33,31 @@ func TestUndefinedTopLevelOption(t *testing.T) {
 
 	assert.Error(t, Validate(config))
 }
+
+func TestValidateVersion(t *testing.T) {
+	config := func(version string) dict {
+		return dict{
+			"version": version,
+			"networks": dict{
+				"front": dict{"attachable": true},
+			},
+		}
+	}
+
+	assert.EqualError(t, Validate(config("3.0")), "networks.front Additional property attachable is not allowed")
+	assert.NoError(t, Validate(config("3.8")))
+}
+
+func TestProperties(t *testing.T) {
+	properties, err := Properties("volume")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"driver", "driver_opts", "external"}, properties)
+
+	properties, err = Properties("service")
+	assert.NoError(t, err)
+	assert.Contains(t, properties, "deploy")
+	assert.NotContains(t, properties, "volumes_from")
+
+	_, err = Properties("helicopter")
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldName returns the key of a struct field in a Compose file, which is the
+// name in its mapstructure tag, or else its lowercased Go name
+func FieldName(field reflect.StructField) string {
+	if name := field.Tag.Get("mapstructure"); name != "" {
+		return strings.Split(name, ",")[0]
+	}
+	return strings.ToLower(field.Name)
+}
+
+// IndirectType returns the type typ points to, following any number of
+// pointers
+func IndirectType(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// JoinPath appends a key to a dotted path such as deploy.resources
+func JoinPath(path string, key string) string {
+	if path == "" || key == "" {
+		return path + key
+	}
+	return path + "." + key
+}
+
+// ContainsString returns true if list contains value
+func ContainsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
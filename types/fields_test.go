@@ -0,0 +1,34 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldName(t *testing.T) {
+	deploy := reflect.TypeOf(DeployConfig{})
+	restartPolicy, _ := deploy.FieldByName("RestartPolicy")
+	mode, _ := deploy.FieldByName("Mode")
+	assert.Equal(t, "restart_policy", FieldName(restartPolicy))
+	assert.Equal(t, "mode", FieldName(mode))
+}
+
+func TestIndirectType(t *testing.T) {
+	var config **ServiceConfig
+	assert.Equal(t, reflect.TypeOf(ServiceConfig{}), IndirectType(reflect.TypeOf(config)))
+	assert.Nil(t, IndirectType(nil))
+}
+
+func TestJoinPath(t *testing.T) {
+	assert.Equal(t, "deploy", JoinPath("", "deploy"))
+	assert.Equal(t, "deploy", JoinPath("deploy", ""))
+	assert.Equal(t, "deploy.resources", JoinPath("deploy", "resources"))
+}
+
+func TestContainsString(t *testing.T) {
+	assert.True(t, ContainsString([]string{"a", "b"}, "b"))
+	assert.False(t, ContainsString([]string{"a", "b"}, "c"))
+	assert.False(t, ContainsString(nil, "a"))
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+)
+
+// hashIgnoredFields are the paths of the service fields which don't affect the
+// containers of a service, and are left out of its hash
+var hashIgnoredFields = []string{
+	"name",
+	"deploy.replicas",
+}
+
+// Hash returns a hash of the configuration of the service, which changes when
+// the containers of the service need to be recreated.
+//
+// The hash is the hex-encoded SHA-256 of the canonical JSON form of the
+// service: struct fields are keyed by their Compose file name, mapping keys are
+// sorted, and fields which are unset or empty are left out, so that adding a
+// field to ServiceConfig does not change the hash of services which don't use
+// it. Tmpfs mounts are hashed in the short syntax, for example
+// /run:size=67108864,mode=1777. The name of the service and deploy.replicas
+// are ignored. The hash is stable across releases, and any change to it is a
+// breaking change.
+func (s ServiceConfig) Hash() string {
+	canonical, _ := canonicalValue(reflect.ValueOf(s), "")
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		// canonicalValue only returns maps, slices and JSON scalars
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalValue returns the canonical form of value at path, and false if it
+// is unset or empty and should be left out
+func canonicalValue(value reflect.Value, path string) (interface{}, bool) {
+	// tmpfs mounts were strings in the short syntax before they were typed,
+	// and are hashed as those strings
+	if tmpfs, ok := value.Interface().(TmpfsConfig); ok {
+		return tmpfs.String(), true
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil, false
+		}
+		elem := value.Elem()
+		if elem.Kind() == reflect.Struct {
+			return canonicalValue(elem, path)
+		}
+		// a pointer to a zero value is set, unlike the zero value itself
+		if canonical, ok := canonicalValue(elem, path); ok {
+			return canonical, true
+		}
+		return elem.Interface(), true
+
+	case reflect.Struct:
+		canonical := map[string]interface{}{}
+		for i := 0; i < value.NumField(); i++ {
+			name := FieldName(value.Type().Field(i))
+			fieldPath := JoinPath(path, name)
+			if ContainsString(hashIgnoredFields, fieldPath) {
+				continue
+			}
+			if field, ok := canonicalValue(value.Field(i), fieldPath); ok {
+				canonical[name] = field
+			}
+		}
+		return canonical, len(canonical) > 0
+
+	case reflect.Map:
+		if value.Len() == 0 {
+			return nil, false
+		}
+		canonical := make(map[string]interface{}, value.Len())
+		for _, key := range value.MapKeys() {
+			// entries are kept even if they are empty, since the key is set
+			elem, _ := canonicalValue(value.MapIndex(key), path)
+			canonical[key.String()] = elem
+		}
+		return canonical, true
+
+	case reflect.Slice, reflect.Array:
+		if value.Len() == 0 {
+			return nil, false
+		}
+		canonical := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			canonical[i], _ = canonicalValue(value.Index(i), path)
+		}
+		return canonical, true
+
+	default:
+		if value.IsZero() {
+			return nil, false
+		}
+		return value.Interface(), true
+	}
+}
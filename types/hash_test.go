@@ -0,0 +1,96 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func hashTestService() ServiceConfig {
+	replicas := uint64(3)
+	grace := 10 * time.Second
+	value := "bar"
+	return ServiceConfig{
+		Name:    "web",
+		Image:   "nginx:1.11",
+		Command: []string{"nginx", "-g", "daemon off;"},
+		Environment: MappingWithEquals{
+			"FOO":  &value,
+			"PASS": nil,
+		},
+		Labels: map[string]string{
+			"a": "1",
+			"b": "2",
+			"c": "3",
+		},
+		Ports:           []string{"80:80"},
+		StopGracePeriod: &grace,
+		Deploy: DeployConfig{
+			Mode:     "replicated",
+			Replicas: &replicas,
+		},
+	}
+}
+
+func TestServiceConfigHashIsPinned(t *testing.T) {
+	assert.Equal(t, "86f4436baf5570f69349bc6cfad53e024834285e866a0ca1e4865942747fcf6a", ServiceConfig{Image: "busybox"}.Hash())
+	assert.Equal(t, "d7d14ec3e3661486cdf660aeab5b75c99208caf1cfe37da65b27dc2b79021926", hashTestService().Hash())
+}
+
+func TestServiceConfigHashOfTmpfsIsPinned(t *testing.T) {
+	// the hash of a service with tmpfs: [/run, "/tmp:size=67108864,mode=1777"]
+	// from before tmpfs mounts were typed
+	service := ServiceConfig{
+		Image: "busybox",
+		Tmpfs: []TmpfsConfig{
+			{Target: "/run"},
+			{Target: "/tmp", Size: 64 << 20, Mode: 01777},
+		},
+	}
+	assert.Equal(t, "23c7a119210181ccdcf5f152a0bb99620e26c101bd802dcc33e110c6158cf2f0", service.Hash())
+}
+
+func TestServiceConfigHashIsStable(t *testing.T) {
+	expected := hashTestService().Hash()
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, expected, hashTestService().Hash())
+	}
+}
+
+func TestServiceConfigHashIgnoresFields(t *testing.T) {
+	service := hashTestService()
+	expected := service.Hash()
+
+	replicas := uint64(5)
+	service.Deploy.Replicas = &replicas
+	service.Name = "other"
+	assert.Equal(t, expected, service.Hash())
+
+	service.Deploy.Replicas = nil
+	assert.Equal(t, expected, service.Hash())
+
+	service.Labels = map[string]string{}
+	service.Ports = nil
+	empty := hashTestService()
+	empty.Labels = nil
+	empty.Ports = []string{}
+	assert.Equal(t, empty.Hash(), service.Hash())
+}
+
+func TestServiceConfigHashChanges(t *testing.T) {
+	service := hashTestService()
+	expected := service.Hash()
+
+	service.Command = []string{"daemon off;", "-g", "nginx"}
+	assert.NotEqual(t, expected, service.Hash())
+
+	service = hashTestService()
+	service.Environment["PASS"] = new(string)
+	assert.NotEqual(t, expected, service.Hash())
+
+	service = hashTestService()
+	zero := time.Duration(0)
+	service.StopGracePeriod = &zero
+	assert.NotEqual(t, expected, service.Hash())
+}
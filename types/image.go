@@ -0,0 +1,151 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is the registry of images whose name has no domain
+	DefaultRegistry = "docker.io"
+	// DefaultTag is the tag of images given with neither a tag nor a digest
+	DefaultTag = "latest"
+
+	legacyDefaultRegistry = "index.docker.io"
+	officialRepository    = "library"
+	maxNameLength         = 255
+)
+
+// The grammar of image references, as defined by the Docker distribution
+// reference package
+var (
+	domainComponent = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	domainExp       = domainComponent + `(?:\.` + domainComponent + `)*(?::[0-9]+)?`
+	pathComponent   = `[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*`
+	tagExp          = `[\w][\w.-]{0,127}`
+	digestExp       = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}`
+
+	domainRegexp    = regexp.MustCompile(`^` + domainExp + `$`)
+	pathRegexp      = regexp.MustCompile(`^` + pathComponent + `(?:/` + pathComponent + `)*$`)
+	tagRegexp       = regexp.MustCompile(`^` + tagExp + `$`)
+	digestRegexp    = regexp.MustCompile(`^` + digestExp + `$`)
+	uppercaseRegexp = regexp.MustCompile(`[A-Z]`)
+)
+
+// ImageReference is a parsed and normalized image reference, such as
+// docker.io/library/nginx:1.11
+type ImageReference struct {
+	// Domain is the registry the image is pulled from, for example docker.io
+	// or localhost:5000
+	Domain string
+	// Path is the repository within the registry, for example library/nginx
+	Path string
+	// Tag and Digest are empty if they are not part of the reference
+	Tag    string
+	Digest string
+}
+
+// ParseImageReference parses an image reference using the Docker reference
+// grammar, and normalizes it: a name without a domain is on Docker Hub, and an
+// image on Docker Hub without a namespace is in library.
+func ParseImageReference(ref string) (ImageReference, error) {
+	var parsed ImageReference
+	if ref == "" {
+		return parsed, fmt.Errorf("invalid reference format: repository name must not be empty")
+	}
+
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		parsed.Digest = name[i+1:]
+		name = name[:i]
+		if !digestRegexp.MatchString(parsed.Digest) {
+			return parsed, fmt.Errorf("invalid reference format: invalid digest %q", parsed.Digest)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		parsed.Tag = name[i+1:]
+		name = name[:i]
+		if !tagRegexp.MatchString(parsed.Tag) {
+			return parsed, fmt.Errorf("invalid reference format: invalid tag %q", parsed.Tag)
+		}
+	}
+
+	if len(name) > maxNameLength {
+		return parsed, fmt.Errorf("invalid reference format: repository name must not be more than %d characters", maxNameLength)
+	}
+	parsed.Domain, parsed.Path = splitDomain(name)
+	if !domainRegexp.MatchString(parsed.Domain) {
+		return parsed, fmt.Errorf("invalid reference format: invalid registry %q", parsed.Domain)
+	}
+	if !pathRegexp.MatchString(parsed.Path) {
+		if uppercaseRegexp.MatchString(parsed.Path) {
+			return parsed, fmt.Errorf("invalid reference format: repository name must be lowercase")
+		}
+		return parsed, fmt.Errorf("invalid reference format: invalid repository name %q", parsed.Path)
+	}
+	return parsed, nil
+}
+
+// splitDomain splits a repository name into its registry and path. The first
+// component of the name is a registry if it contains a . or a :, or is
+// localhost.
+func splitDomain(name string) (string, string) {
+	domain, path := DefaultRegistry, name
+	if i := strings.Index(name, "/"); i >= 0 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" || strings.ToLower(first) != first {
+			domain, path = first, name[i+1:]
+		}
+	}
+	if domain == legacyDefaultRegistry {
+		domain = DefaultRegistry
+	}
+	if domain == DefaultRegistry && !strings.Contains(path, "/") {
+		path = officialRepository + "/" + path
+	}
+	return domain, path
+}
+
+// Name returns the fully-qualified repository name, for example
+// docker.io/library/nginx
+func (r ImageReference) Name() string {
+	return r.Domain + "/" + r.Path
+}
+
+// FamiliarName returns the repository name as users usually write it, without
+// the default registry and the library namespace, for example nginx
+func (r ImageReference) FamiliarName() string {
+	if r.Domain != DefaultRegistry {
+		return r.Name()
+	}
+	return strings.TrimPrefix(r.Path, officialRepository+"/")
+}
+
+// String returns the fully-qualified reference, with the default tag if the
+// reference has neither a tag nor a digest, for example
+// docker.io/library/nginx:latest
+func (r ImageReference) String() string {
+	tag := r.Tag
+	if tag == "" && r.Digest == "" {
+		tag = DefaultTag
+	}
+	return r.Name() + r.suffix(tag)
+}
+
+// Familiar returns the reference as users usually write it, for example
+// nginx:1.11
+func (r ImageReference) Familiar() string {
+	return r.FamiliarName() + r.suffix(r.Tag)
+}
+
+func (r ImageReference) suffix(tag string) string {
+	var suffix string
+	if tag != "" {
+		suffix += ":" + tag
+	}
+	if r.Digest != "" {
+		suffix += "@" + r.Digest
+	}
+	return suffix
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageReference(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	testCases := []struct {
+		ref      string
+		expected ImageReference
+		str      string
+		familiar string
+	}{
+		{
+			ref:      "nginx",
+			expected: ImageReference{Domain: "docker.io", Path: "library/nginx"},
+			str:      "docker.io/library/nginx:latest",
+			familiar: "nginx",
+		},
+		{
+			ref:      "nginx:1.11",
+			expected: ImageReference{Domain: "docker.io", Path: "library/nginx", Tag: "1.11"},
+			str:      "docker.io/library/nginx:1.11",
+			familiar: "nginx:1.11",
+		},
+		{
+			ref:      "index.docker.io/dockersamples/web",
+			expected: ImageReference{Domain: "docker.io", Path: "dockersamples/web"},
+			str:      "docker.io/dockersamples/web:latest",
+			familiar: "dockersamples/web",
+		},
+		{
+			ref:      "localhost:5000/team/app:v2@" + digest,
+			expected: ImageReference{Domain: "localhost:5000", Path: "team/app", Tag: "v2", Digest: digest},
+			str:      "localhost:5000/team/app:v2@" + digest,
+			familiar: "localhost:5000/team/app:v2@" + digest,
+		},
+		{
+			ref:      "gcr.io/project/image@" + digest,
+			expected: ImageReference{Domain: "gcr.io", Path: "project/image", Digest: digest},
+			str:      "gcr.io/project/image@" + digest,
+			familiar: "gcr.io/project/image@" + digest,
+		},
+		{
+			ref:      "localhost/my_app-2",
+			expected: ImageReference{Domain: "localhost", Path: "my_app-2"},
+			str:      "localhost/my_app-2:latest",
+			familiar: "localhost/my_app-2",
+		},
+	}
+
+	for _, testCase := range testCases {
+		parsed, err := ParseImageReference(testCase.ref)
+		if !assert.NoError(t, err, testCase.ref) {
+			continue
+		}
+		assert.Equal(t, testCase.expected, parsed, testCase.ref)
+		assert.Equal(t, testCase.str, parsed.String(), testCase.ref)
+		assert.Equal(t, testCase.familiar, parsed.Familiar(), testCase.ref)
+	}
+}
+
+func TestParseImageReferenceInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"":                 "repository name must not be empty",
+		"Nginx":            "repository name must be lowercase",
+		"nginx:":           `invalid tag ""`,
+		"nginx:-1":         `invalid tag "-1"`,
+		"nginx@sha256:abc": `invalid digest "sha256:abc"`,
+		"nginx//web":       `invalid repository name "nginx//web"`,
+		"-bad.io/nginx":    `invalid registry "-bad.io"`,
+		"${image}":         `invalid repository name "library/${image}"`,
+	}
+	for ref, message := range testCases {
+		_, err := ParseImageReference(ref)
+		assert.EqualError(t, err, "invalid reference format: "+message, ref)
+	}
+}
+
+func TestProjectImages(t *testing.T) {
+	project := NewProject("app", "", &Config{
+		Services: []ServiceConfig{
+			{Name: "web", Image: "nginx:1.11"},
+			{Name: "proxy", Image: "docker.io/library/nginx:1.11"},
+			{Name: "db", Image: "postgres"},
+			{Name: "worker"},
+		},
+	})
+
+	var images []string
+	for _, image := range project.Images() {
+		images = append(images, image.String())
+	}
+	assert.Equal(t, []string{
+		"docker.io/library/nginx:1.11",
+		"docker.io/library/postgres:latest",
+	}, images)
+}
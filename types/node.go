@@ -0,0 +1,273 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeKind identifies the shape of a Node
+type NodeKind int
+
+const (
+	ScalarNode NodeKind = iota + 1
+	MappingNode
+	SequenceNode
+)
+
+// Position is a location in a Compose file
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+// IsValid returns true if the position refers to a line in a file
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	location := p.Filename
+	if p.IsValid() {
+		if location != "" {
+			location += ":"
+		}
+		location += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if location == "" {
+		return "-"
+	}
+	return location
+}
+
+// Comments holds the comments attached to a node in the source document
+type Comments struct {
+	Head string
+	Line string
+	Foot string
+}
+
+// MapItem is a single key/value entry of a mapping Node
+type MapItem struct {
+	Key         string
+	KeyPosition Position
+	Comments    Comments
+	Value       *Node
+}
+
+// Node is an element of a Compose document. Mappings keep the order of their
+// keys, and every node remembers where it came from, so that errors and
+// warnings can point at the source.
+type Node struct {
+	Kind NodeKind
+	// Value is the decoded value of a scalar: a string, int, float64, bool or nil
+	Value    interface{}
+	Items    []*MapItem
+	Elements []*Node
+	// Anchor is the name of the anchor defined on this node, and Alias is the
+	// name of the anchor this node was copied from, if any
+	Anchor   string
+	Alias    string
+	Comments Comments
+	Position Position
+}
+
+// NewNode builds a Node from plain values, as produced by Interface. Map keys
+// are sorted, since Go maps carry no order.
+func NewNode(value interface{}) *Node {
+	switch value := value.(type) {
+	case *Node:
+		return value
+	case map[string]interface{}:
+		node := &Node{Kind: MappingNode}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			node.Set(key, NewNode(value[key]))
+		}
+		return node
+	case []interface{}:
+		node := &Node{Kind: SequenceNode, Elements: make([]*Node, len(value))}
+		for i, elem := range value {
+			node.Elements[i] = NewNode(elem)
+		}
+		return node
+	default:
+		return &Node{Kind: ScalarNode, Value: value}
+	}
+}
+
+// IsNull returns true if the node is missing or is an explicit null
+func (n *Node) IsNull() bool {
+	return n == nil || (n.Kind == ScalarNode && n.Value == nil)
+}
+
+// Get returns the value stored under key in a mapping node
+func (n *Node) Get(key string) (*Node, bool) {
+	if item := n.Item(key); item != nil {
+		return item.Value, true
+	}
+	return nil, false
+}
+
+// Item returns the entry stored under key in a mapping node, or nil
+func (n *Node) Item(key string) *MapItem {
+	if n == nil || n.Kind != MappingNode {
+		return nil
+	}
+	for _, item := range n.Items {
+		if item.Key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// Lookup follows a path of mapping keys and returns the node at the end of it,
+// or nil if any part of the path is missing
+func (n *Node) Lookup(path ...string) *Node {
+	current := n
+	for _, key := range path {
+		next, ok := current.Get(key)
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// Set replaces the value under key in a mapping node, or appends a new entry
+// if the key is not yet present
+func (n *Node) Set(key string, value *Node) {
+	if item := n.Item(key); item != nil {
+		item.Value = value
+		return
+	}
+	n.Items = append(n.Items, &MapItem{Key: key, Value: value})
+}
+
+// Delete removes key from a mapping node
+func (n *Node) Delete(key string) {
+	for i, item := range n.Items {
+		if item.Key == key {
+			n.Items = append(n.Items[:i], n.Items[i+1:]...)
+			return
+		}
+	}
+}
+
+// Keys returns the keys of a mapping node in document order
+func (n *Node) Keys() []string {
+	if n == nil || n.Kind != MappingNode {
+		return nil
+	}
+	keys := make([]string, len(n.Items))
+	for i, item := range n.Items {
+		keys[i] = item.Key
+	}
+	return keys
+}
+
+// Interface converts the node to plain values: map[string]interface{} for
+// mappings, []interface{} for sequences and the scalar value otherwise. This
+// is the form expected by the schema validator and by mapstructure.
+func (n *Node) Interface() interface{} {
+	if n == nil {
+		return nil
+	}
+	switch n.Kind {
+	case MappingNode:
+		return n.Dict()
+	case SequenceNode:
+		list := make([]interface{}, len(n.Elements))
+		for i, elem := range n.Elements {
+			list[i] = elem.Interface()
+		}
+		return list
+	default:
+		return n.Value
+	}
+}
+
+// Dict converts a mapping node to a map[string]interface{}. It returns nil for
+// any other kind of node.
+func (n *Node) Dict() map[string]interface{} {
+	if n == nil || n.Kind != MappingNode {
+		return nil
+	}
+	dict := make(map[string]interface{}, len(n.Items))
+	for _, item := range n.Items {
+		dict[item.Key] = item.Value.Interface()
+	}
+	return dict
+}
+
+// Copy returns a deep copy of the node
+func (n *Node) Copy() *Node {
+	if n == nil {
+		return nil
+	}
+	out := *n
+	if n.Items != nil {
+		out.Items = make([]*MapItem, len(n.Items))
+		for i, item := range n.Items {
+			copied := *item
+			copied.Value = item.Value.Copy()
+			out.Items[i] = &copied
+		}
+	}
+	if n.Elements != nil {
+		out.Elements = make([]*Node, len(n.Elements))
+		for i, elem := range n.Elements {
+			out.Elements[i] = elem.Copy()
+		}
+	}
+	return &out
+}
+
+// Merge returns a copy of n with override applied on top of it. Mappings are
+// merged key by key, keeping the order of n and appending new keys; any other
+// value in override replaces the one in n.
+func (n *Node) Merge(override *Node) *Node {
+	if override == nil {
+		return n.Copy()
+	}
+	if n == nil || n.Kind != MappingNode || override.Kind != MappingNode {
+		return override.Copy()
+	}
+	out := n.Copy()
+	for _, item := range override.Items {
+		if existing := out.Item(item.Key); existing != nil {
+			existing.Value = existing.Value.Merge(item.Value)
+			continue
+		}
+		copied := *item
+		copied.Value = item.Value.Copy()
+		out.Items = append(out.Items, &copied)
+	}
+	return out
+}
+
+// SetFilename records filename in the position of the node and all of its
+// children which do not already have one
+func (n *Node) SetFilename(filename string) {
+	if n == nil {
+		return
+	}
+	if n.Position.Filename == "" {
+		n.Position.Filename = filename
+	}
+	for _, item := range n.Items {
+		if item.KeyPosition.Filename == "" {
+			item.KeyPosition.Filename = filename
+		}
+		item.Value.SetFilename(filename)
+	}
+	for _, elem := range n.Elements {
+		elem.SetFilename(filename)
+	}
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNodeRoundTrip(t *testing.T) {
+	value := map[string]interface{}{
+		"version": "3",
+		"services": map[string]interface{}{
+			"foo": map[string]interface{}{
+				"image":    "busybox",
+				"ports":    []interface{}{8000, "9000:9000"},
+				"networks": map[string]interface{}{"front": nil},
+			},
+		},
+	}
+	node := NewNode(value)
+	assert.Equal(t, value, node.Interface())
+	assert.Equal(t, []string{"services", "version"}, node.Keys())
+}
+
+func TestNodeMerge(t *testing.T) {
+	base := NewNode(map[string]interface{}{
+		"image":       "busybox",
+		"environment": map[string]interface{}{"FOO": "1"},
+		"ports":       []interface{}{"8000"},
+	})
+	override := NewNode(map[string]interface{}{
+		"environment": map[string]interface{}{"BAR": "2"},
+		"ports":       []interface{}{"9000"},
+		"command":     "true",
+	})
+
+	merged := base.Merge(override)
+	assert.Equal(t, map[string]interface{}{
+		"image":       "busybox",
+		"environment": map[string]interface{}{"FOO": "1", "BAR": "2"},
+		"ports":       []interface{}{"9000"},
+		"command":     "true",
+	}, merged.Interface())
+	assert.Equal(t, []string{"environment", "image", "ports", "command"}, merged.Keys())
+
+	// neither side is modified
+	assert.Equal(t, map[string]interface{}{"FOO": "1"}, base.Lookup("environment").Interface())
+}
+
+func TestNodeSetAndDelete(t *testing.T) {
+	node := &Node{Kind: MappingNode}
+	node.Set("b", NewNode(1))
+	node.Set("a", NewNode(2))
+	node.Set("b", NewNode(3))
+	assert.Equal(t, []string{"b", "a"}, node.Keys())
+	assert.Equal(t, 3, node.Lookup("b").Value)
+
+	node.Delete("b")
+	assert.Equal(t, []string{"a"}, node.Keys())
+	assert.Nil(t, node.Lookup("b"))
+	assert.Nil(t, node.Lookup("a", "c"))
+}
+
+func TestPositionString(t *testing.T) {
+	assert.Equal(t, "docker-compose.yml:3:5", Position{Filename: "docker-compose.yml", Line: 3, Column: 5}.String())
+	assert.Equal(t, "3:5", Position{Line: 3, Column: 5}.String())
+	assert.Equal(t, "docker-compose.yml", Position{Filename: "docker-compose.yml"}.String())
+	assert.Equal(t, "-", Position{}.String())
+}
@@ -0,0 +1,186 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ConstraintOperator compares the attribute of a node with a value
+type ConstraintOperator string
+
+const (
+	// ConstraintEqual matches nodes whose attribute is equal to the value
+	ConstraintEqual ConstraintOperator = "=="
+	// ConstraintNotEqual matches nodes whose attribute is not equal to the
+	// value
+	ConstraintNotEqual ConstraintOperator = "!="
+)
+
+var (
+	constraintKeyRegexp   = regexp.MustCompile(`^(?i)[a-z_][a-z0-9\-_.]+$`)
+	constraintValueRegexp = regexp.MustCompile(`^(?i)[a-z0-9:\-_\s\.\*\(\)\?\+\[\]\\\^\$\|\/]+$`)
+	constraintKeys        = []string{"node.id", "node.hostname", "node.role", "node.platform.os", "node.platform.arch"}
+	constraintLabelKeys   = []string{"node.labels.", "engine.labels."}
+	nodeRoles             = []string{"manager", "worker"}
+)
+
+// PlacementConstraint is a parsed placement constraint, such as
+// node.role==manager
+type PlacementConstraint struct {
+	Key      string
+	Operator ConstraintOperator
+	Value    string
+}
+
+func (c PlacementConstraint) String() string {
+	return c.Key + string(c.Operator) + c.Value
+}
+
+// ParsePlacementConstraint parses a constraint of the form key==value or
+// key!=value. The key must be one of node.id, node.hostname, node.role,
+// node.platform.os and node.platform.arch, or a label under node.labels. or
+// engine.labels.
+func ParsePlacementConstraint(constraint string) (PlacementConstraint, error) {
+	var parsed PlacementConstraint
+	for _, operator := range []ConstraintOperator{ConstraintEqual, ConstraintNotEqual} {
+		parts := strings.SplitN(constraint, string(operator), 2)
+		if len(parts) == 2 {
+			parsed = PlacementConstraint{
+				Key:      strings.TrimSpace(parts[0]),
+				Operator: operator,
+				Value:    strings.TrimSpace(parts[1]),
+			}
+			break
+		}
+	}
+	if parsed.Operator == "" {
+		return parsed, fmt.Errorf("invalid constraint %q: the operator must be == or !=", constraint)
+	}
+
+	if !constraintKeyRegexp.MatchString(parsed.Key) {
+		return parsed, fmt.Errorf("invalid constraint %q: invalid key %q", constraint, parsed.Key)
+	}
+	if !isConstraintKey(parsed.Key) {
+		return parsed, fmt.Errorf("invalid constraint %q: unknown key %q, expected one of %s, or a label under %s",
+			constraint, parsed.Key, strings.Join(constraintKeys, ", "), strings.Join(constraintLabelKeys, " or "))
+	}
+	if !constraintValueRegexp.MatchString(parsed.Value) {
+		return parsed, fmt.Errorf("invalid constraint %q: invalid value %q", constraint, parsed.Value)
+	}
+	if strings.ToLower(parsed.Key) == "node.role" && !containsFold(nodeRoles, parsed.Value) {
+		return parsed, fmt.Errorf("invalid constraint %q: node.role must be manager or worker", constraint)
+	}
+	return parsed, nil
+}
+
+func isConstraintKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, known := range constraintKeys {
+		if key == known {
+			return true
+		}
+	}
+	return isLabelKey(key)
+}
+
+// isLabelKey returns true if key names a node or engine label
+func isLabelKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, prefix := range constraintLabelKeys {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseConstraints parses every constraint of the placement
+func (p Placement) ParseConstraints() ([]PlacementConstraint, error) {
+	var constraints []PlacementConstraint
+	for _, constraint := range p.Constraints {
+		parsed, err := ParsePlacementConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, parsed)
+	}
+	return constraints, nil
+}
+
+// Validate checks that the preference spreads over a node or engine label
+func (p PlacementPreferences) Validate() error {
+	if !constraintKeyRegexp.MatchString(p.Spread) || !isLabelKey(p.Spread) {
+		return fmt.Errorf("invalid placement preference %q: spread must be a label under %s",
+			p.Spread, strings.Join(constraintLabelKeys, " or "))
+	}
+	return nil
+}
+
+// NodeAttributes describes a node, which constraints are evaluated against
+type NodeAttributes struct {
+	ID       string
+	Hostname string
+	Role     string
+	OS       string
+	Arch     string
+	// Labels are the labels of the node, and EngineLabels the labels of its
+	// Docker engine
+	Labels       map[string]string
+	EngineLabels map[string]string
+}
+
+// Match returns true if node satisfies the constraint. Values are compared
+// case-insensitively, and a missing label is not equal to any value.
+func (c PlacementConstraint) Match(node NodeAttributes) bool {
+	actual, ok := c.attribute(node)
+	equal := ok && strings.EqualFold(actual, c.Value)
+	if c.Operator == ConstraintNotEqual {
+		return !equal
+	}
+	return equal
+}
+
+func (c PlacementConstraint) attribute(node NodeAttributes) (string, bool) {
+	key := strings.ToLower(c.Key)
+	switch key {
+	case "node.id":
+		return node.ID, true
+	case "node.hostname":
+		return node.Hostname, true
+	case "node.role":
+		return node.Role, true
+	case "node.platform.os":
+		return node.OS, true
+	case "node.platform.arch":
+		return node.Arch, true
+	}
+	switch {
+	case strings.HasPrefix(key, "node.labels."):
+		value, ok := node.Labels[c.Key[len("node.labels."):]]
+		return value, ok
+	case strings.HasPrefix(key, "engine.labels."):
+		value, ok := node.EngineLabels[c.Key[len("engine.labels."):]]
+		return value, ok
+	}
+	return "", false
+}
+
+// MatchConstraints returns true if node satisfies every constraint
+func MatchConstraints(constraints []PlacementConstraint, node NodeAttributes) bool {
+	for _, constraint := range constraints {
+		if !constraint.Match(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlacementConstraint(t *testing.T) {
+	testCases := map[string]PlacementConstraint{
+		"node.role==manager":          {Key: "node.role", Operator: ConstraintEqual, Value: "manager"},
+		"node.role == worker":         {Key: "node.role", Operator: ConstraintEqual, Value: "worker"},
+		"node.hostname!=db-1":         {Key: "node.hostname", Operator: ConstraintNotEqual, Value: "db-1"},
+		"node.labels.zone==eu-west-1": {Key: "node.labels.zone", Operator: ConstraintEqual, Value: "eu-west-1"},
+		"engine.labels.storage==ssd":  {Key: "engine.labels.storage", Operator: ConstraintEqual, Value: "ssd"},
+		"node.platform.os==linux":     {Key: "node.platform.os", Operator: ConstraintEqual, Value: "linux"},
+	}
+	for constraint, expected := range testCases {
+		parsed, err := ParsePlacementConstraint(constraint)
+		assert.NoError(t, err, constraint)
+		assert.Equal(t, expected, parsed, constraint)
+	}
+}
+
+func TestParsePlacementConstraintInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"node.role = manager": `invalid constraint "node.role = manager": the operator must be == or !=`,
+		"node.rol==manager":   `invalid constraint "node.rol==manager": unknown key "node.rol", expected one of node.id, node.hostname, node.role, node.platform.os, node.platform.arch, or a label under node.labels. or engine.labels.`,
+		"node.labels.==x":     `invalid constraint "node.labels.==x": unknown key "node.labels.", expected one of node.id, node.hostname, node.role, node.platform.os, node.platform.arch, or a label under node.labels. or engine.labels.`,
+		"node.role==boss":     `invalid constraint "node.role==boss": node.role must be manager or worker`,
+		"node.id==":           `invalid constraint "node.id==": invalid value ""`,
+		"==manager":           `invalid constraint "==manager": invalid key ""`,
+	}
+	for constraint, message := range testCases {
+		_, err := ParsePlacementConstraint(constraint)
+		assert.EqualError(t, err, message, constraint)
+	}
+}
+
+func TestPlacementConstraintMatch(t *testing.T) {
+	node := NodeAttributes{
+		ID:           "abc",
+		Hostname:     "db-1",
+		Role:         "worker",
+		OS:           "linux",
+		Labels:       map[string]string{"zone": "EU"},
+		EngineLabels: map[string]string{"storage": "ssd"},
+	}
+	matches := map[string]bool{
+		"node.role==worker":          true,
+		"node.role==manager":         false,
+		"node.role!=manager":         true,
+		"node.hostname==DB-1":        true,
+		"node.labels.zone==eu":       true,
+		"node.labels.rack==a":        false,
+		"node.labels.rack!=a":        true,
+		"engine.labels.storage!=ssd": false,
+		"node.platform.os==linux":    true,
+	}
+	for constraint, expected := range matches {
+		parsed, err := ParsePlacementConstraint(constraint)
+		if assert.NoError(t, err, constraint) {
+			assert.Equal(t, expected, parsed.Match(node), constraint)
+		}
+	}
+
+	constraints, err := Placement{Constraints: []string{"node.role==worker", "node.labels.zone==eu"}}.ParseConstraints()
+	assert.NoError(t, err)
+	assert.True(t, MatchConstraints(constraints, node))
+
+	node.Role = "manager"
+	assert.False(t, MatchConstraints(constraints, node))
+}
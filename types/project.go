@@ -0,0 +1,162 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultNetwork is the network services are attached to when they don't list
+// any networks
+const DefaultNetwork = "default"
+
+var projectNameRegexp = regexp.MustCompile("[^-_a-z0-9]")
+
+// NormalizeProjectName lowercases name and removes the characters which are
+// not allowed in the names of Docker resources. It returns "default" if
+// nothing is left.
+func NormalizeProjectName(name string) string {
+	name = projectNameRegexp.ReplaceAllString(strings.ToLower(name), "")
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// ProjectNameFromDir returns the project name for a project in workingDir,
+// which is the normalized name of the directory
+func ProjectNameFromDir(workingDir string) string {
+	return NormalizeProjectName(filepath.Base(filepath.Clean(workingDir)))
+}
+
+// Project is a loaded configuration together with the name of the project,
+// which namespaces the resources the project creates
+type Project struct {
+	Name   string
+	Config *Config
+}
+
+// NewProject returns a Project for config. The name is normalized, and
+// defaults to config.Name, or to the name of workingDir if that is empty too.
+func NewProject(name string, workingDir string, config *Config) *Project {
+	if name == "" {
+		name = config.Name
+	}
+	if name == "" {
+		name = ProjectNameFromDir(workingDir)
+	}
+	return &Project{Name: NormalizeProjectName(name), Config: config}
+}
+
+// Service returns the service called name, or nil
+func (p *Project) Service(name string) *ServiceConfig {
+	for i := range p.Config.Services {
+		if p.Config.Services[i].Name == name {
+			return &p.Config.Services[i]
+		}
+	}
+	return nil
+}
+
+// ServiceNetworks returns the sorted names of the networks service is attached
+// to, which is the default network if it doesn't list any
+func (p *Project) ServiceNetworks(service ServiceConfig) []string {
+	if len(service.Networks) == 0 {
+		return []string{DefaultNetwork}
+	}
+	var names []string
+	for name := range service.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Networks returns the networks of the project, including the implicit
+// default network if any service is attached to it and it is not declared
+func (p *Project) Networks() map[string]NetworkConfig {
+	networks := make(map[string]NetworkConfig, len(p.Config.Networks)+1)
+	for name, network := range p.Config.Networks {
+		networks[name] = network
+	}
+	if _, ok := networks[DefaultNetwork]; !ok {
+		for _, service := range p.Config.Services {
+			if len(service.Networks) == 0 {
+				networks[DefaultNetwork] = NetworkConfig{}
+				break
+			}
+			if _, ok := service.Networks[DefaultNetwork]; ok {
+				networks[DefaultNetwork] = NetworkConfig{}
+				break
+			}
+		}
+	}
+	return networks
+}
+
+// Images returns the images the services of the project use, sorted and
+// without duplicates. Services which are only built, or whose image is not a
+// valid reference, are skipped.
+func (p *Project) Images() []ImageReference {
+	seen := map[string]bool{}
+	var images []ImageReference
+	for _, service := range p.Config.Services {
+		if service.Image == "" {
+			continue
+		}
+		image, err := ParseImageReference(service.Image)
+		if err != nil || seen[image.String()] {
+			continue
+		}
+		seen[image.String()] = true
+		images = append(images, image)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].String() < images[j].String()
+	})
+	return images
+}
+
+// NetworkName returns the name of the Docker network for the network called
+// name in the project. External networks keep their own name, and the name of
+// other networks can be overridden with the name property.
+func (p *Project) NetworkName(name string) string {
+	network, ok := p.Config.Networks[name]
+	switch {
+	case ok && network.External.External:
+		return network.External.Name
+	case ok && network.Name != "":
+		return network.Name
+	}
+	return p.scoped(name)
+}
+
+// VolumeName returns the name of the Docker volume for the volume called name
+// in the project. External volumes keep their own name.
+func (p *Project) VolumeName(name string) string {
+	if volume, ok := p.Config.Volumes[name]; ok && volume.External.External {
+		return volume.External.Name
+	}
+	return p.scoped(name)
+}
+
+// SecretName returns the name of the Docker secret for the secret called name
+// in the project
+func (p *Project) SecretName(name string) string {
+	return p.scoped(name)
+}
+
+// ContainerName returns the name of the container with the given number of a
+// service, which is the container_name of the service if it is set
+func (p *Project) ContainerName(service ServiceConfig, number int) string {
+	if service.ContainerName != "" {
+		return service.ContainerName
+	}
+	return fmt.Sprintf("%s_%s_%d", p.Name, service.Name, number)
+}
+
+func (p *Project) scoped(name string) string {
+	return p.Name + "_" + name
+}
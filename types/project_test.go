@@ -0,0 +1,71 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeProjectName(t *testing.T) {
+	assert.Equal(t, "myapp", NormalizeProjectName("MyApp"))
+	assert.Equal(t, "my_app-2", NormalizeProjectName("my_app-2"))
+	assert.Equal(t, "myapp", NormalizeProjectName("my.app!"))
+	assert.Equal(t, "default", NormalizeProjectName("..."))
+	assert.Equal(t, "myapp", ProjectNameFromDir("/home/user/My App/"))
+}
+
+func TestNewProjectName(t *testing.T) {
+	config := &Config{}
+	assert.Equal(t, "fromdir", NewProject("", "/src/FromDir", config).Name)
+	assert.Equal(t, "explicit", NewProject("Explicit", "/src/FromDir", config).Name)
+
+	config.Name = "fromconfig"
+	assert.Equal(t, "fromconfig", NewProject("", "/src/FromDir", config).Name)
+}
+
+func TestProjectResourceNames(t *testing.T) {
+	project := NewProject("app", "", &Config{
+		Services: []ServiceConfig{
+			{Name: "web", Networks: map[string]*ServiceNetworkConfig{"front": nil}},
+			{Name: "db", ContainerName: "database"},
+		},
+		Networks: map[string]NetworkConfig{
+			"front":  {},
+			"shared": {External: External{External: true, Name: "shared_net"}},
+		},
+		Volumes: map[string]VolumeConfig{
+			"data":   {},
+			"backup": {External: External{External: true, Name: "backups"}},
+		},
+	})
+
+	assert.Equal(t, "app_front", project.NetworkName("front"))
+	assert.Equal(t, "shared_net", project.NetworkName("shared"))
+	assert.Equal(t, "app_default", project.NetworkName(DefaultNetwork))
+	assert.Equal(t, "app_data", project.VolumeName("data"))
+	assert.Equal(t, "backups", project.VolumeName("backup"))
+	assert.Equal(t, "app_token", project.SecretName("token"))
+	assert.Equal(t, "app_web_1", project.ContainerName(*project.Service("web"), 1))
+	assert.Equal(t, "database", project.ContainerName(*project.Service("db"), 1))
+	assert.Nil(t, project.Service("missing"))
+
+	assert.Equal(t, []string{"front"}, project.ServiceNetworks(*project.Service("web")))
+	assert.Equal(t, []string{DefaultNetwork}, project.ServiceNetworks(*project.Service("db")))
+}
+
+func TestProjectImplicitDefaultNetwork(t *testing.T) {
+	project := NewProject("app", "", &Config{
+		Services: []ServiceConfig{
+			{Name: "web", Networks: map[string]*ServiceNetworkConfig{"front": nil}},
+		},
+		Networks: map[string]NetworkConfig{"front": {}},
+	})
+	assert.Equal(t, map[string]NetworkConfig{"front": {}}, project.Networks())
+
+	project.Config.Services = append(project.Config.Services, ServiceConfig{Name: "db"})
+	assert.Equal(t, map[string]NetworkConfig{"front": {}, DefaultNetwork: {}}, project.Networks())
+
+	driver := NetworkConfig{Driver: "overlay"}
+	project.Config.Networks[DefaultNetwork] = driver
+	assert.Equal(t, driver, project.Networks()[DefaultNetwork])
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String returns the mount in the short syntax, for example
+// /run:size=67108864,mode=1777
+func (t TmpfsConfig) String() string {
+	var options []string
+	if t.Size != 0 {
+		options = append(options, fmt.Sprintf("size=%d", t.Size))
+	}
+	if t.Mode != 0 {
+		options = append(options, fmt.Sprintf("mode=%o", t.Mode))
+	}
+	if len(options) == 0 {
+		return t.Target
+	}
+	return t.Target + ":" + strings.Join(options, ",")
+}
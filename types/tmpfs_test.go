@@ -0,0 +1,14 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmpfsConfigString(t *testing.T) {
+	assert.Equal(t, "/run", TmpfsConfig{Target: "/run"}.String())
+	assert.Equal(t, "/run:size=67108864", TmpfsConfig{Target: "/run", Size: 64 << 20}.String())
+	assert.Equal(t, "/run:mode=1777", TmpfsConfig{Target: "/run", Mode: 01777}.String())
+	assert.Equal(t, "/run:size=1024,mode=700", TmpfsConfig{Target: "/run", Size: 1024, Mode: 0700}.String())
+}
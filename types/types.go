@@ -1,6 +1,7 @@
 package types
 
 import (
+	"io/fs"
 	"time"
 )
 
@@ -25,15 +26,52 @@ var UnsupportedProperties = []string{
 	"shm_size",
 	"stop_signal",
 	"sysctls",
-	"tmpfs",
 	"userns_mode",
 }
 
+// UnsupportedPropertyHints tells the user what to do about each of the
+// UnsupportedProperties
+var UnsupportedPropertyHints = map[string]string{
+	"build":          "Build and push the image beforehand, and reference it using image.",
+	"cap_add":        "Remove it, services run with the default capabilities.",
+	"cap_drop":       "Remove it, services run with the default capabilities.",
+	"cgroup_parent":  "Remove it, the cgroup is chosen by the engine.",
+	"devices":        "Remove it, devices can not be mapped into services.",
+	"dns":            "Remove it, services use the DNS configuration of their nodes.",
+	"dns_search":     "Remove it, services use the DNS configuration of their nodes.",
+	"domainname":     "Remove it, services use the domain name of their nodes.",
+	"external_links": "Connect the services to a shared network using networks instead.",
+	"ipc":            "Remove it, services can not share an IPC namespace.",
+	"links":          "Use networks instead - services on the same network can reach each other by name.",
+	"mac_address":    "Remove it, MAC addresses are assigned by the engine.",
+	"network_mode":   "Use networks instead.",
+	"privileged":     "Remove it, services can not run privileged.",
+	"read_only":      "Remove it, the root filesystem of a service is always writable.",
+	"restart":        "Use deploy.restart_policy instead.",
+	"security_opt":   "Remove it, services run with the default security options.",
+	"shm_size":       "Mount a tmpfs at /dev/shm using tmpfs instead.",
+	"stop_signal":    "Remove it, services are stopped with SIGTERM.",
+	"sysctls":        "Remove it, and set kernel parameters on the nodes instead.",
+	"userns_mode":    "Remove it, user namespaces are configured on the daemon.",
+}
+
 var DeprecatedProperties = map[string]string{
 	"container_name": "Setting the container name is not supported.",
 	"expose":         "Exposing ports is unnecessary - services on the same network can access each other's containers on any port.",
 }
 
+// DeprecatedNetworkProperties are the deprecated properties of a network,
+// keyed by their path within the network
+var DeprecatedNetworkProperties = map[string]string{
+	"external.name": "Setting the name under external is deprecated - set name next to external: true instead.",
+}
+
+// DeprecatedVolumeProperties are the deprecated properties of a volume, keyed
+// by their path within the volume
+var DeprecatedVolumeProperties = map[string]string{
+	"external.name": "Setting the name under external is deprecated - name the volume after the external volume instead.",
+}
+
 var ForbiddenProperties = map[string]string{
 	"extends":       "`extends` is not supported.",
 	"volume_driver": "Instead of setting the volume driver on the service, define a volume using the top-level `volumes` option and specify the driver there.",
@@ -45,20 +83,26 @@ var ForbiddenProperties = map[string]string{
 	"memswap_limit": "Set resource limits using deploy.resources",
 }
 
-type Dict map[string]interface{}
-
 type ConfigFile struct {
 	Filename string
-	Config   Dict
+	Config   *Node
 }
 
 type ConfigDetails struct {
 	WorkingDir  string
 	ConfigFiles []ConfigFile
 	Environment map[string]string
+	// FS is the filesystem env files are read from, with WorkingDir being a
+	// path inside it. If nil, files are read from the local filesystem. If it
+	// is set, the loader does not read the process environment or the system
+	// user database either.
+	FS fs.FS
 }
 
 type Config struct {
+	// Name is the normalized name of the project, given to the loader or
+	// derived from the working directory
+	Name     string
 	Services []ServiceConfig
 	Networks map[string]NetworkConfig
 	Volumes  map[string]VolumeConfig
@@ -79,7 +123,7 @@ type ServiceConfig struct {
 	DnsSearch       []string          `mapstructure:"dns_search" compose:"string_or_list"`
 	DomainName      string            `mapstructure:"domainname"`
 	Entrypoint      []string          `compose:"shell_command"`
-	Environment     map[string]string `compose:"list_or_dict_equals"`
+	Environment     MappingWithEquals `compose:"list_or_dict_equals_nullable"`
 	Expose          []string          `compose:"list_of_strings_or_numbers"`
 	ExternalLinks   []string          `mapstructure:"external_links"`
 	ExtraHosts      map[string]string `mapstructure:"extra_hosts" compose:"list_or_dict_colon"`
@@ -102,7 +146,7 @@ type ServiceConfig struct {
 	StdinOpen       bool           `mapstructure:"stdin_open"`
 	StopGracePeriod *time.Duration `mapstructure:"stop_grace_period"`
 	StopSignal      string         `mapstructure:"stop_signal"`
-	Tmpfs           []string       `compose:"string_or_list"`
+	Tmpfs           []TmpfsConfig  `compose:"item_or_list"`
 	Tty             bool           `mapstructure:"tty"`
 	Ulimits         map[string]*UlimitsConfig
 	User            string
@@ -110,19 +154,25 @@ type ServiceConfig struct {
 	WorkingDir      string `mapstructure:"working_dir"`
 }
 
+// MappingWithEquals is a mapping of variables given as KEY=VALUE. A nil value
+// means the variable was given without a value, and is passed through from
+// the environment the project is loaded in.
+type MappingWithEquals map[string]*string
+
 type LoggingConfig struct {
 	Driver  string
 	Options map[string]string
 }
 
 type DeployConfig struct {
-	Mode          string
-	Replicas      *uint64
-	Labels        map[string]string `compose:"list_or_dict_equals"`
-	UpdateConfig  *UpdateConfig     `mapstructure:"update_config"`
-	Resources     Resources
-	RestartPolicy *RestartPolicy `mapstructure:"restart_policy"`
-	Placement     Placement
+	Mode           string
+	Replicas       *uint64
+	Labels         map[string]string `compose:"list_or_dict_equals"`
+	UpdateConfig   *UpdateConfig     `mapstructure:"update_config"`
+	RollbackConfig *UpdateConfig     `mapstructure:"rollback_config"`
+	Resources      Resources
+	RestartPolicy  *RestartPolicy `mapstructure:"restart_policy"`
+	Placement      Placement
 }
 
 type HealthCheckConfig struct {
@@ -139,6 +189,7 @@ type UpdateConfig struct {
 	FailureAction   string `mapstructure:"failure_action"`
 	Monitor         time.Duration
 	MaxFailureRatio float32 `mapstructure:"max_failure_ratio"`
+	Order           string
 }
 
 type Resources struct {
@@ -154,6 +205,18 @@ type Resource struct {
 
 type UnitBytes int64
 
+// TmpfsConfig is a tmpfs mount of a service, given in the tmpfs option, or as
+// a volume of type tmpfs
+type TmpfsConfig struct {
+	Target string
+	// Size is the size of the mount in bytes, and is unlimited if zero
+	Size UnitBytes
+	// Mode holds the permission bits of the mount, such as 01777, and is
+	// the default mode if zero. It is written as an octal YAML number, such
+	// as 0755, or as a string of octal digits.
+	Mode uint32
+}
+
 type RestartPolicy struct {
 	Condition   string
 	Delay       *time.Duration
@@ -163,14 +226,30 @@ type RestartPolicy struct {
 
 type Placement struct {
 	Constraints []string
+	Preferences []PlacementPreferences
+	MaxReplicas uint64 `mapstructure:"max_replicas_per_node"`
+}
+
+// PlacementPreferences spreads the tasks of a service evenly over the values
+// of a node label
+type PlacementPreferences struct {
+	Spread string
 }
 
 type ServiceNetworkConfig struct {
-	Aliases     []string
-	Ipv4Address string `mapstructure:"ipv4_address"`
-	Ipv6Address string `mapstructure:"ipv6_address"`
+	Aliases      []string
+	Ipv4Address  string   `mapstructure:"ipv4_address"`
+	Ipv6Address  string   `mapstructure:"ipv6_address"`
+	LinkLocalIPs []string `mapstructure:"link_local_ips"`
+	MacAddress   string   `mapstructure:"mac_address"`
+	// Priority orders the networks the service is connected to, the highest
+	// first
+	Priority int
 }
 
+// UlimitsConfig is a ulimit of a service. Single is set if the ulimit was
+// given as a single number, and Soft and Hard if it was given as a mapping;
+// Limits and Canonical return the limits whichever form was used.
 type UlimitsConfig struct {
 	Single int
 	Soft   int
@@ -178,20 +257,32 @@ type UlimitsConfig struct {
 }
 
 type NetworkConfig struct {
+	// Name overrides the name of the network, which is otherwise scoped to
+	// the project
+	Name       string
 	Driver     string
 	DriverOpts map[string]string `mapstructure:"driver_opts"`
 	Ipam       IPAMConfig
 	External   External
+	// Attachable allows standalone containers to connect to an overlay
+	// network
+	Attachable bool
+	Internal   bool
+	EnableIPv6 bool              `mapstructure:"enable_ipv6"`
 	Labels     map[string]string `compose:"list_or_dict_equals"`
 }
 
 type IPAMConfig struct {
-	Driver string
-	Config []*IPAMPool
+	Driver  string
+	Config  []*IPAMPool
+	Options map[string]string
 }
 
 type IPAMPool struct {
-	Subnet string
+	Subnet       string
+	Gateway      string
+	IPRange      string            `mapstructure:"ip_range"`
+	AuxAddresses map[string]string `mapstructure:"aux_addresses"`
 }
 
 type VolumeConfig struct {
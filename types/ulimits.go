@@ -0,0 +1,54 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UlimitNames are the resource limits which can be set with ulimits
+var UlimitNames = []string{
+	"core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue", "nice",
+	"nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack",
+}
+
+// Unlimited is the value of a limit which is not limited
+const Unlimited = -1
+
+// Limits returns the soft and hard limits of the ulimit. A ulimit given as a
+// single number has the same soft and hard limit.
+func (u UlimitsConfig) Limits() (soft int, hard int) {
+	if u.Single != 0 {
+		return u.Single, u.Single
+	}
+	return u.Soft, u.Hard
+}
+
+// Canonical returns the ulimit in its mapping form, with the soft and hard
+// limits set and Single unset
+func (u UlimitsConfig) Canonical() UlimitsConfig {
+	soft, hard := u.Limits()
+	return UlimitsConfig{Soft: soft, Hard: hard}
+}
+
+// Validate checks that name is a known ulimit, that both limits are
+// non-negative or unlimited, and that the soft limit is not above the hard
+// limit
+func (u UlimitsConfig) Validate(name string) error {
+	if !ContainsString(UlimitNames, name) {
+		return fmt.Errorf("unknown ulimit %q, expected one of %s", name, strings.Join(UlimitNames, ", "))
+	}
+	soft, hard := u.Limits()
+	for _, limit := range []struct {
+		kind  string
+		value int
+	}{{"soft", soft}, {"hard", hard}} {
+		if limit.value < Unlimited {
+			return fmt.Errorf("invalid %s limit %d for ulimit %s, expected -1 for unlimited or a non-negative number",
+				limit.kind, limit.value, name)
+		}
+	}
+	if hard != Unlimited && (soft == Unlimited || soft > hard) {
+		return fmt.Errorf("soft limit %d for ulimit %s is greater than its hard limit %d", soft, name, hard)
+	}
+	return nil
+}
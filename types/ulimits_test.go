@@ -0,0 +1,52 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUlimitsConfigLimits(t *testing.T) {
+	soft, hard := UlimitsConfig{Single: 65535}.Limits()
+	assert.Equal(t, 65535, soft)
+	assert.Equal(t, 65535, hard)
+
+	soft, hard = UlimitsConfig{Soft: 20000, Hard: 40000}.Limits()
+	assert.Equal(t, 20000, soft)
+	assert.Equal(t, 40000, hard)
+}
+
+func TestUlimitsConfigCanonical(t *testing.T) {
+	assert.Equal(t, UlimitsConfig{Soft: 65535, Hard: 65535}, UlimitsConfig{Single: 65535}.Canonical())
+	assert.Equal(t, UlimitsConfig{Soft: 20000, Hard: 40000}, UlimitsConfig{Soft: 20000, Hard: 40000}.Canonical())
+}
+
+func TestUlimitsConfigValidate(t *testing.T) {
+	valid := map[string]UlimitsConfig{
+		"nproc":   {Single: 65535},
+		"nofile":  {Soft: 20000, Hard: 40000},
+		"memlock": {Soft: Unlimited, Hard: Unlimited},
+		"stack":   {Soft: 8192, Hard: Unlimited},
+		"core":    {Single: 0},
+	}
+	for name, ulimit := range valid {
+		assert.NoError(t, ulimit.Validate(name), name)
+	}
+
+	invalid := []struct {
+		name    string
+		ulimit  UlimitsConfig
+		message string
+	}{
+		{"nofiles", UlimitsConfig{Single: 1}, `unknown ulimit "nofiles"`},
+		{"nofile", UlimitsConfig{Soft: 2, Hard: 1}, "soft limit 2 for ulimit nofile is greater than its hard limit 1"},
+		{"memlock", UlimitsConfig{Soft: Unlimited, Hard: 1}, "soft limit -1 for ulimit memlock is greater than its hard limit 1"},
+		{"nproc", UlimitsConfig{Soft: 1, Hard: -5}, "invalid hard limit -5 for ulimit nproc"},
+	}
+	for _, testCase := range invalid {
+		err := testCase.ulimit.Validate(testCase.name)
+		if assert.Error(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.message)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VolumeSpec is a volume of a service in the short syntax,
+// [source:]target[:mode]. Windows paths, such as C:\data, \\server\share and
+// \\.\pipe\docker_engine, are recognized whatever the OS the spec is parsed on.
+type VolumeSpec struct {
+	// Source is a path on the host or the name of a volume, and is empty for
+	// an anonymous volume
+	Source string
+	Target string
+	Mode   string
+}
+
+// ParseVolumeSpec splits a volume in the short syntax into its parts. Colons
+// separate the parts, except the colon following the drive letter of a
+// Windows path.
+//
+// A drive letter followed by a slash is ambiguous: n:/data is both the
+// Windows path n:/data and the volume n mounted at /data. It is only read as
+// a Windows path where the other reading would be invalid, which is as the
+// target, or as the source when an absolute target follows it, as in
+// c:/data:/data. On its own, c:/data is the volume c mounted at /data.
+func ParseVolumeSpec(spec string) (VolumeSpec, error) {
+	parts := splitVolumeSpec(spec)
+	for _, part := range parts {
+		if part == "" {
+			return VolumeSpec{}, fmt.Errorf("invalid volume %q: empty section between colons", spec)
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		return VolumeSpec{Target: parts[0]}, nil
+	case 2:
+		return VolumeSpec{Source: parts[0], Target: parts[1]}, nil
+	case 3:
+		return VolumeSpec{Source: parts[0], Target: parts[1], Mode: parts[2]}, nil
+	}
+	return VolumeSpec{}, fmt.Errorf("invalid volume %q: too many colons", spec)
+}
+
+func splitVolumeSpec(spec string) []string {
+	var parts []string
+	start := 0
+	for {
+		from := start
+		if startsWithDrivePath(spec[start:], start == 0) {
+			from += 2
+		}
+		end := strings.IndexByte(spec[from:], ':')
+		if end < 0 {
+			return append(parts, spec[start:])
+		}
+		parts = append(parts, spec[start:from+end])
+		start = from + end + 1
+	}
+}
+
+// startsWithDrivePath returns true if the section of a volume spec at the
+// start of rest is a Windows path starting with a drive letter, where source
+// is true if the section is the first one
+func startsWithDrivePath(rest string, source bool) bool {
+	if !HasDriveLetter(rest) {
+		return false
+	}
+	if rest[2] == '\\' || !source {
+		return true
+	}
+	end := strings.IndexByte(rest[2:], ':')
+	return end >= 0 && IsAbsPath(rest[2+end+1:])
+}
+
+func (v VolumeSpec) String() string {
+	parts := []string{v.Target}
+	if v.Source != "" {
+		parts = []string{v.Source, v.Target}
+	}
+	if v.Mode != "" {
+		parts = append(parts, v.Mode)
+	}
+	return strings.Join(parts, ":")
+}
+
+// IsNamedVolume returns true if the source of the volume is the name of a
+// volume rather than a path on the host
+func (v VolumeSpec) IsNamedVolume() bool {
+	return v.Source != "" && !IsHostPath(v.Source)
+}
+
+// IsHostPath returns true if source is a path on the host rather than the
+// name of a volume: an absolute path, a path starting with . or ~, or a
+// relative path containing a separator
+func IsHostPath(source string) bool {
+	return IsAbsPath(source) ||
+		strings.HasPrefix(source, ".") ||
+		strings.HasPrefix(source, "~") ||
+		strings.ContainsAny(source, `/\`)
+}
+
+// IsAbsPath returns true if p is an absolute POSIX path, a Windows path
+// starting with a drive letter, or a UNC path or named pipe
+func IsAbsPath(p string) bool {
+	return strings.HasPrefix(p, "/") || strings.HasPrefix(p, `\\`) || HasDriveLetter(p)
+}
+
+// HasDriveLetter returns true if p starts with a Windows drive letter followed
+// by a separator, such as C:\ or c:/
+func HasDriveLetter(p string) bool {
+	if len(p) < 3 || p[1] != ':' || (p[2] != '\\' && p[2] != '/') {
+		return false
+	}
+	return ('a' <= p[0] && p[0] <= 'z') || ('A' <= p[0] && p[0] <= 'Z')
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVolumeSpec(t *testing.T) {
+	testCases := map[string]VolumeSpec{
+		"/var/lib/mysql":               {Target: "/var/lib/mysql"},
+		"/opt/data:/var/lib/mysql":     {Source: "/opt/data", Target: "/var/lib/mysql"},
+		"datavolume:/var/lib/mysql:ro": {Source: "datavolume", Target: "/var/lib/mysql", Mode: "ro"},
+		"~/configs:/etc/configs/:ro":   {Source: "~/configs", Target: "/etc/configs/", Mode: "ro"},
+		`C:\data:/data`:                {Source: `C:\data`, Target: "/data"},
+		`c:/data:/data:rw`:             {Source: "c:/data", Target: "/data", Mode: "rw"},
+		`C:\data:D:\data`:              {Source: `C:\data`, Target: `D:\data`},
+		`C:\data:D:\data:ro`:           {Source: `C:\data`, Target: `D:\data`, Mode: "ro"},
+		`D:\data`:                      {Target: `D:\data`},
+		`datavolume:C:\data`:           {Source: "datavolume", Target: `C:\data`},
+		"datavolume:c:/data":           {Source: "datavolume", Target: "c:/data"},
+		"n:/data":                      {Source: "n", Target: "/data"},
+		"n:/data:ro":                   {Source: "n", Target: "/data", Mode: "ro"},
+		`\\server\share:/data`:         {Source: `\\server\share`, Target: "/data"},
+		`\\.\pipe\docker_engine:\\.\pipe\docker_engine`: {
+			Source: `\\.\pipe\docker_engine`,
+			Target: `\\.\pipe\docker_engine`,
+		},
+	}
+	for spec, expected := range testCases {
+		parsed, err := ParseVolumeSpec(spec)
+		if assert.NoError(t, err, spec) {
+			assert.Equal(t, expected, parsed, spec)
+			assert.Equal(t, spec, parsed.String())
+		}
+	}
+}
+
+func TestParseVolumeSpecInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"":                   `invalid volume "": empty section between colons`,
+		"/data:":             `invalid volume "/data:": empty section between colons`,
+		":/data":             `invalid volume ":/data": empty section between colons`,
+		"/a:/b:ro:nocopy":    `invalid volume "/a:/b:ro:nocopy": too many colons`,
+		`C:\a:D:\b:ro:extra`: `invalid volume "C:\\a:D:\\b:ro:extra": too many colons`,
+	}
+	for spec, message := range testCases {
+		_, err := ParseVolumeSpec(spec)
+		assert.EqualError(t, err, message, spec)
+	}
+}
+
+func TestIsHostPath(t *testing.T) {
+	for _, source := range []string{
+		"/opt/data", "./data", "..", "~/configs", "~alice", "static/files",
+		`C:\data`, "c:/data", `\\server\share`, `\\.\pipe\docker_engine`, `.\data`,
+	} {
+		assert.True(t, IsHostPath(source), source)
+	}
+	for _, source := range []string{"datavolume", "c", "C:"} {
+		assert.False(t, IsHostPath(source), source)
+	}
+
+	assert.True(t, VolumeSpec{Source: "data", Target: "/data"}.IsNamedVolume())
+	assert.False(t, VolumeSpec{Source: `C:\data`, Target: "/data"}.IsNamedVolume())
+	assert.False(t, VolumeSpec{Target: "/data"}.IsNamedVolume())
+}
+
+func TestIsAbsPath(t *testing.T) {
+	for _, p := range []string{"/opt/data", `C:\data`, "z:/data", `\\server\share`, `\\.\pipe\docker_engine`} {
+		assert.True(t, IsAbsPath(p), p)
+	}
+	for _, p := range []string{"data", "./data", `.\data`, "C:", "C:data", "~/data", "1:/data"} {
+		assert.False(t, IsAbsPath(p), p)
+	}
+}